@@ -0,0 +1,232 @@
+package testingfiles
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// APIKind is the kind of a feature of a Go API file.
+type APIKind string
+
+const (
+	KindConst       APIKind = "const"
+	KindVar         APIKind = "var"
+	KindFunc        APIKind = "func"
+	KindMethod      APIKind = "method"
+	KindType        APIKind = "type"
+	KindField       APIKind = "field"            // field of a struct
+	KindIfaceMethod APIKind = "interface method" // method of an interface
+	KindEmbedded    APIKind = "embedded"         // type embedded in a struct or an interface
+)
+
+// APIFeature is a line of a Go API file like those of $GOROOT/api.
+//
+//	pkg syscall (linux-386), const AF_ALG = 38
+//	pkg io, type ReadWriter interface, embedded Reader
+type APIFeature struct {
+	Pkg     string  // import path
+	Context string  // build context like linux-386-cgo, empty when the feature is available everywhere
+	Kind    APIKind // kind of the feature
+	Recv    string  // receiver of a method, struct or interface of a field, method or embedded type
+	Name    string  // name of the feature
+	Value   string  // value or type of a constant, type of a var or field, signature of a func, type definition
+	Line    string  // original line
+}
+
+// String returns the original line.
+func (f APIFeature) String() string {
+	return f.Line
+}
+
+// Key identifies the feature whatever the build context. When ignoreValue is true, the value is ignored and
+// features differing only by value have the same key.
+func (f APIFeature) Key(ignoreValue bool) string {
+	k := strings.Join([]string{f.Pkg, string(f.Kind), f.Recv, f.Name}, ", ")
+	if ignoreValue {
+		return k
+	}
+	return k + ", " + f.Value
+}
+
+// ParseAPILine parses a line of a Go API file.
+func ParseAPILine(line string) (APIFeature, error) {
+	f := APIFeature{Line: line}
+	fail := func(msg string) (APIFeature, error) {
+		return APIFeature{}, fmt.Errorf("invalid API line %q: %s", line, msg)
+	}
+	s, found := strings.CutPrefix(line, "pkg ")
+	if !found {
+		return fail("pkg is missing")
+	}
+	head, s, found := strings.Cut(s, ", ")
+	if !found {
+		return fail("feature is missing")
+	}
+	f.Pkg = head
+	if p, ctx, found := strings.Cut(head, " ("); found {
+		f.Pkg = p
+		if f.Context, found = strings.CutSuffix(ctx, ")"); !found {
+			return fail("build context is not closed")
+		}
+	}
+	kind, s, found := strings.Cut(s, " ")
+	if !found {
+		return fail("name is missing")
+	}
+	f.Kind = APIKind(kind)
+	switch f.Kind {
+	case KindConst, KindVar:
+		f.Name, f.Value, _ = strings.Cut(s, " ")
+		f.Value = strings.TrimPrefix(f.Value, "= ")
+	case KindFunc:
+		f.Name, f.Value = cutName(s)
+	case KindMethod:
+		if !strings.HasPrefix(s, "(") {
+			return fail("receiver is missing")
+		}
+		recv, sig, found := strings.Cut(s[1:], ") ")
+		if !found {
+			return fail("receiver is not closed")
+		}
+		f.Recv = recv
+		f.Name, f.Value = cutName(sig)
+	case KindType:
+		f.Name, f.Value = cutName(s)
+		params, def := splitTypeParams(f.Value)
+		for _, compound := range []string{"struct, ", "interface, "} {
+			member, found := strings.CutPrefix(def, compound)
+			if !found {
+				continue
+			}
+			f.Recv = f.Name + params
+			if embedded, found := strings.CutPrefix(member, "embedded "); found {
+				f.Kind, f.Name, f.Value = KindEmbedded, embedded, ""
+			} else if compound == "struct, " {
+				f.Kind = KindField
+				f.Name, f.Value, _ = strings.Cut(member, " ")
+			} else {
+				f.Kind = KindIfaceMethod
+				f.Name, f.Value = cutName(member)
+			}
+			break
+		}
+	default:
+		return fail("unknown kind " + kind)
+	}
+	if f.Name == "" {
+		return fail("name is missing")
+	}
+	return f, nil
+}
+
+// cutName separates a name from what follows like type parameters, a signature or a type.
+func cutName(s string) (name, rest string) {
+	i := strings.IndexAny(s, " [(")
+	if i == -1 {
+		return s, ""
+	}
+	return s[:i], strings.TrimPrefix(s[i:], " ")
+}
+
+// splitTypeParams separates type parameters, if any, from the type definition.
+func splitTypeParams(s string) (params, def string) {
+	if !strings.HasPrefix(s, "[") {
+		return "", s
+	}
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return s[:i+1], strings.TrimPrefix(s[i+1:], " ")
+			}
+		}
+	}
+	return "", s
+}
+
+// ReadAPIFile parses the lines of a Go API file. Empty lines and # comments are skipped.
+func ReadAPIFile(filename string) ([]APIFeature, error) {
+	lines, err := readLines(filename)
+	if err != nil {
+		return nil, err
+	}
+	features := make([]APIFeature, 0, len(lines))
+	for i, l := range lines {
+		if l = strings.TrimSpace(l); l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		f, err := ParseAPILine(l)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, i+1, err)
+		}
+		features = append(features, f)
+	}
+	return features, nil
+}
+
+// APIGroup is a package and a kind of features.
+type APIGroup struct {
+	Pkg  string
+	Kind APIKind
+}
+
+// GroupAPI groups features by package and kind. Order of features is kept in each group.
+func GroupAPI(features []APIFeature) map[APIGroup][]APIFeature {
+	groups := make(map[APIGroup][]APIFeature)
+	for _, f := range features {
+		g := APIGroup{Pkg: f.Pkg, Kind: f.Kind}
+		groups[g] = append(groups[g], f)
+	}
+	return groups
+}
+
+// CommonAPI returns the features of the first API file selected by globf which all the other files declare.
+// Features are compared using their key which ignores the build context and, when ignoreValue is true,
+// the value. The returned features are the ones of the first file.
+func CommonAPI(contextFilesPath, globf string, ignoreValue bool) ([]APIFeature, error) {
+	fl, err := globFiles(contextFilesPath, globf)
+	if err != nil {
+		return nil, err
+	}
+	common, err := ReadAPIFile(fl[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fl[1:] {
+		features, err := ReadAPIFile(f)
+		if err != nil {
+			return nil, err
+		}
+		keys := apiKeys(features, ignoreValue)
+		common = slices.DeleteFunc(common, func(f APIFeature) bool {
+			return !keys[f.Key(ignoreValue)]
+		})
+	}
+	return common, nil
+}
+
+// SupplementAPI returns the features of the API file filename which are absent from common.
+// Features are compared like CommonAPI does.
+func SupplementAPI(common []APIFeature, filename string, ignoreValue bool) ([]APIFeature, error) {
+	features, err := ReadAPIFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	keys := apiKeys(common, ignoreValue)
+	return slices.DeleteFunc(features, func(f APIFeature) bool {
+		return keys[f.Key(ignoreValue)]
+	}), nil
+}
+
+func apiKeys(features []APIFeature, ignoreValue bool) map[string]bool {
+	keys := make(map[string]bool, len(features))
+	for _, f := range features {
+		keys[f.Key(ignoreValue)] = true
+	}
+	return keys
+}
@@ -0,0 +1,118 @@
+package testingfiles
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseAPILine(t *testing.T) {
+	for _, want := range []APIFeature{
+		{Pkg: "syscall", Context: "linux-386", Kind: KindConst, Name: "AF_ALG", Value: "38",
+			Line: "pkg syscall (linux-386), const AF_ALG = 38"},
+		{Pkg: "archive/tar", Kind: KindConst, Name: "TypeBlock", Value: "ideal-char",
+			Line: "pkg archive/tar, const TypeBlock ideal-char"},
+		{Pkg: "archive/tar", Kind: KindVar, Name: "ErrHeader", Value: "error",
+			Line: "pkg archive/tar, var ErrHeader error"},
+		{Pkg: "container/heap", Kind: KindFunc, Name: "Push", Value: "(Interface, interface{})",
+			Line: "pkg container/heap, func Push(Interface, interface{})"},
+		{Pkg: "slices", Kind: KindFunc, Name: "Clone", Value: "[$0 interface{ ~[]$1 }, $1 interface{}]($0) $0",
+			Line: "pkg slices, func Clone[$0 interface{ ~[]$1 }, $1 interface{}]($0) $0"},
+		{Pkg: "archive/tar", Kind: KindMethod, Recv: "*Reader", Name: "Next", Value: "() (*Header, error)",
+			Line: "pkg archive/tar, method (*Reader) Next() (*Header, error)"},
+		{Pkg: "compress/flate", Kind: KindType, Name: "Reader", Value: "interface { Read, ReadByte }",
+			Line: "pkg compress/flate, type Reader interface { Read, ReadByte }"},
+		{Pkg: "compress/flate", Kind: KindIfaceMethod, Recv: "Reader", Name: "ReadByte", Value: "() (uint8, error)",
+			Line: "pkg compress/flate, type Reader interface, ReadByte() (uint8, error)"},
+		{Pkg: "archive/zip", Kind: KindEmbedded, Recv: "File", Name: "FileHeader",
+			Line: "pkg archive/zip, type File struct, embedded FileHeader"},
+		{Pkg: "archive/tar", Kind: KindField, Recv: "Header", Name: "AccessTime", Value: "time.Time",
+			Line: "pkg archive/tar, type Header struct, AccessTime time.Time"},
+		{Pkg: "iter", Kind: KindType, Name: "Seq", Value: "[$0 interface{}] func(func($0) bool)",
+			Line: "pkg iter, type Seq[$0 interface{}] func(func($0) bool)"},
+		{Pkg: "log/syslog", Context: "darwin-386-cgo", Kind: KindConst, Name: "LOG_ALERT", Value: "1",
+			Line: "pkg log/syslog (darwin-386-cgo), const LOG_ALERT = 1"},
+	} {
+		got, err := ParseAPILine(want.Line)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestParseAPILine_invalid(t *testing.T) {
+	for _, l := range []string{
+		"",
+		"syscall, const A = 1",
+		"pkg syscall",
+		"pkg syscall (linux, const A = 1",
+		"pkg syscall, const",
+		"pkg syscall, unknown A",
+		"pkg syscall, method T) A()",
+		"pkg syscall, method (T A()",
+	} {
+		if _, err := ParseAPILine(l); err == nil {
+			t.Errorf("%q: parsing succeeded", l)
+		}
+	}
+}
+
+// Every line of the API files of the Go distribution is parsed
+func TestReadAPIFile_goroot(t *testing.T) {
+	fl, _ := filepath.Glob(filepath.Join(build.Default.GOROOT, "api", "go1*.txt"))
+	if len(fl) == 0 {
+		t.Skip("API files of the Go distribution are unavailable")
+	}
+	for _, f := range fl {
+		if _, err := ReadAPIFile(f); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCommonAPI(t *testing.T) {
+	common, err := CommonAPI(path, globf, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(common) != 1 || common[0].String() != commonline {
+		t.Errorf("got %v, want %v", common, commonline)
+	}
+	// BIOCGDLTLIST, BIOCGRTIMEOUT and BIOCSETF only differ by value
+	common, err = CommonAPI(path, globf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(common), 4; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	groups := GroupAPI(common)
+	if got := len(groups[APIGroup{Pkg: "syscall", Kind: KindConst}]); got != len(common) {
+		t.Errorf("got %v, want %v", got, len(common))
+	}
+	supplement, err := SupplementAPI(common, filepath.Join(path, "case_1.txt"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(supplement), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReadAPIFile_invalid(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "api.txt")
+	if err := os.WriteFile(name, []byte("# comment\n\npkg a, const A = 1\nnot a feature\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadAPIFile(name); err == nil {
+		t.Error("invalid line is parsed")
+	}
+	if _, err := CommonAPI(filepath.Dir(name), "*.txt", false); err == nil {
+		t.Error("invalid file is parsed")
+	}
+}
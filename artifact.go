@@ -0,0 +1,172 @@
+package testingfiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// artifactsEnv is the environment variable naming the artifact directory.
+const artifactsEnv = "TESTINGFILES_ARTIFACTS"
+
+var artifactsFlag = flag.String("testingfiles.artifacts", "",
+	"directory where got files and their metadata are written (default a directory of the run in the temporary directory)")
+
+var artifacts = struct {
+	sync.Mutex
+	dir string // set by SetArtifactDir
+	run string // default directory of the run
+}{}
+
+// SetArtifactDir sets the directory where got files and their metadata are written.
+// It takes precedence over the -testingfiles.artifacts flag and the TESTINGFILES_ARTIFACTS variable.
+// An empty dir restores the default.
+func SetArtifactDir(dir string) {
+	artifacts.Lock()
+	artifacts.dir = dir
+	artifacts.Unlock()
+}
+
+// ArtifactDir returns the directory where got files are written.
+// By order of precedence, it is the directory set by SetArtifactDir, the -testingfiles.artifacts flag,
+// the TESTINGFILES_ARTIFACTS variable or a directory created once per run in the temporary directory.
+// A directory which is set is created when the first got file is written.
+func ArtifactDir() string {
+	artifacts.Lock()
+	defer artifacts.Unlock()
+	return artifactDir()
+}
+
+func artifactDir() string {
+	if artifacts.dir != "" {
+		return artifacts.dir
+	}
+	if *artifactsFlag != "" {
+		return *artifactsFlag
+	}
+	if d := os.Getenv(artifactsEnv); d != "" {
+		return d
+	}
+	if artifacts.run == "" {
+		d, err := os.MkdirTemp("", "testingfiles")
+		if err != nil {
+			// Writing the got file reports the failure
+			return filepath.Join(os.TempDir(), "testingfiles")
+		}
+		artifacts.run = d
+	}
+	return artifacts.run
+}
+
+// Artifact is the metadata of a got file written when a comparison fails.
+// It is stored next to the got file with a .json extension.
+type Artifact struct {
+	Caller string `json:"caller"` // package qualified name of the calling func
+	Want   string `json:"want"`   // absolute path of the want file
+	Got    string `json:"got"`    // path of the got file
+	Diff   string `json:"diff"`   // path of the lines of want missing from got and of the lines only in got
+	Offset int64  `json:"offset"` // index of the first differing byte
+	Error  string `json:"error"`  // error returned by the comparison
+}
+
+// writeArtifact writes content as the got file of a.Caller in the artifact directory, its diff and its metadata.
+// Names include the package of the caller and a sequence number when the name is already used,
+// which keeps them unique across packages and runs sharing the directory.
+func writeArtifact(a Artifact, content io.Reader) (string, error) {
+	artifacts.Lock()
+	dir := artifactDir()
+	artifacts.Unlock()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	f, err := createArtifact(dir, "got_"+escapeName(a.Caller))
+	if err != nil {
+		return "", err
+	}
+	a.Got = f.Name()
+	if p, err := filepath.Abs(a.Want); err == nil {
+		a.Want = p
+	}
+	got := new(bytes.Buffer)
+	if _, err = io.Copy(f, io.TeeReader(content, got)); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	if err = f.Close(); err != nil {
+		return "", err
+	}
+	if want, err := os.ReadFile(a.Want); err == nil {
+		a.Diff = a.Got + ".diff"
+		if err = os.WriteFile(a.Diff, lineDiff(want, got.Bytes()), 0644); err != nil {
+			return "", err
+		}
+	}
+	b, err := json.MarshalIndent(a, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(a.Got+".json", b, 0644); err != nil {
+		return "", err
+	}
+	return a.Got, nil
+}
+
+// createArtifact creates the file name of dir, or name followed by the first free sequence number.
+// Creation is exclusive to never overwrite the artifact of another run.
+func createArtifact(dir, name string) (*os.File, error) {
+	for n := 1; ; n++ {
+		p := filepath.Join(dir, name)
+		if n != 1 {
+			p = fmt.Sprintf("%s_%d", p, n)
+		}
+		f, err := os.OpenFile(p, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+}
+
+// lineDiff returns the lines of want missing from got prefixed by - and the lines only in got prefixed by +.
+func lineDiff(want, got []byte) []byte {
+	added, removed := DiffLines(splitLines(string(want)), splitLines(string(got)))
+	var b bytes.Buffer
+	for _, l := range removed {
+		fmt.Fprintf(&b, "-%s\n", l)
+	}
+	for _, l := range added {
+		fmt.Fprintf(&b, "+%s\n", l)
+	}
+	return b.Bytes()
+}
+
+// dumpGot writes the got file of a failed comparison and logs its location.
+// Failing to write the file is logged as the comparison error prevails.
+func dumpGot(a Artifact, content io.Reader) string {
+	p, err := writeArtifact(a, content)
+	if err != nil {
+		log.Printf("%s: writing got file failed: %v", a.Caller, err)
+		return ""
+	}
+	log.Printf("%s: got file is %s", a.Caller, p)
+	return p
+}
+
+// escapeName replaces characters which are invalid in a file name on common file systems.
+func escapeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r < ' ', strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, s)
+}
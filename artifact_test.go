@@ -0,0 +1,146 @@
+package testingfiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArtifactDir(t *testing.T) {
+	run := ArtifactDir()
+	if filepath.Dir(run) != filepath.Clean(os.TempDir()) || !strings.HasPrefix(filepath.Base(run), "testingfiles") {
+		t.Errorf("got %s, want a directory of %s", run, os.TempDir())
+	}
+	if got := ArtifactDir(); got != run {
+		t.Errorf("got %s, want %s for the whole run", got, run)
+	}
+	t.Setenv(artifactsEnv, "fromenv")
+	if got, want := ArtifactDir(), "fromenv"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	*artifactsFlag = "fromflag"
+	defer func() {
+		*artifactsFlag = ""
+	}()
+	if got, want := ArtifactDir(), "fromflag"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	SetArtifactDir("fromfunc")
+	defer SetArtifactDir("")
+	if got, want := ArtifactDir(), "fromfunc"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestCompareArtifact(t *testing.T) {
+	dir := t.TempDir()
+	SetArtifactDir(filepath.Join(dir, "artifacts"))
+	defer SetArtifactDir("")
+	name := "got_" + escapeName("github.com/iwdgo/testingfiles.TestCompareArtifact")
+	want := filepath.Join(dir, "want")
+	if err := os.WriteFile(want, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := BufferCompare(bytes.NewBufferString("abd"), want); err == nil {
+		t.Fatal("no difference found")
+	}
+	if err := ReadCloserCompare(io.NopCloser(bytes.NewBufferString("abe")), want); err == nil {
+		t.Fatal("no difference found")
+	}
+	for _, f := range []string{name, name + "_2"} {
+		b, err := os.ReadFile(filepath.Join(ArtifactDir(), f+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var a Artifact
+		if err = json.Unmarshal(b, &a); err != nil {
+			t.Fatal(err)
+		}
+		if a.Offset != 2 || a.Want != want || a.Got != filepath.Join(ArtifactDir(), f) || a.Error == "" ||
+			a.Diff != a.Got+".diff" {
+			t.Errorf("unexpected metadata %+v", a)
+		}
+		if _, err = os.Stat(a.Got); err != nil {
+			t.Error(err)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(ArtifactDir(), name+".diff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "-abc\n+abd\n"; got != want {
+		t.Errorf("got diff %q, want %q", got, want)
+	}
+}
+
+// Artifacts of another run are never overwritten
+func TestCompareArtifact_exclusive(t *testing.T) {
+	dir := t.TempDir()
+	SetArtifactDir(dir)
+	defer SetArtifactDir("")
+	name := "got_" + escapeName("github.com/iwdgo/testingfiles.TestCompareArtifact_exclusive")
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("previous"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(dir, "want")
+	if err := os.WriteFile(want, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cerr := ReadCloserCompare(io.NopCloser(bytes.NewBufferString("abcd")), want)
+	if cerr == nil {
+		t.Fatal("no difference found")
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, name)); string(b) != "previous" {
+		t.Errorf("previous artifact is overwritten by %q", b)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, name+"_2.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a Artifact
+	if err = json.Unmarshal(b, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Error != cerr.Error() {
+		t.Errorf("got error %q in metadata, want %q", a.Error, cerr)
+	}
+}
+
+// Got files hold the complete got content whatever the difference
+func TestCompareArtifact_complete(t *testing.T) {
+	dir := t.TempDir()
+	defer SetArtifactDir("")
+	want := filepath.Join(dir, "want")
+	if err := os.WriteFile(want, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"abd", "ab", "abcd", "xbc"} {
+		name := "got_" + escapeName("github.com/iwdgo/testingfiles.TestCompareArtifact_complete")
+		SetArtifactDir(filepath.Join(dir, content))
+		if err := BufferCompare(bytes.NewBufferString(content), want); err == nil {
+			t.Fatalf("%s: no difference found", content)
+		}
+		if err := ReadCloserCompare(io.NopCloser(bytes.NewBufferString(content)), want); err == nil {
+			t.Fatalf("%s: no difference found", content)
+		}
+		for _, f := range []string{name, name + "_2"} {
+			b, err := os.ReadFile(filepath.Join(dir, content, f))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != content {
+				t.Errorf("%s: got %q, want %q", f, b, content)
+			}
+		}
+	}
+}
+
+func TestEscapeName(t *testing.T) {
+	if got, want := escapeName("a/b\\c:d*e?f\"g<h>i|j\tk"), "a_b_c_d_e_f_g_h_i_j_k"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,389 @@
+// Command testingfiles compares, updates and prunes golden files, and extracts the common lines of files.
+//
+// Usage:
+//
+//	testingfiles [-v] command [flags] arguments
+//
+// Commands are:
+//
+//	compare got want                 compare two files
+//	diff [-glob pattern] [-json] got want
+//	                                 print the lines of want missing from got (-) and the lines only in got (+),
+//	                                 got and want being files or directories
+//	common [flags] dir glob common   write the lines common to the files of dir selected by glob
+//	supplement [flags] dir glob base remove the lines of base from the files of dir selected by glob
+//	recompose dir base supplement    print the lines rebuilt from base and supplement
+//	update got want                  replace want by got when they differ
+//	prune [-n] -used file dir glob   remove the golden files of dir which no test listed in file used
+//
+// Exit status is 0 on success, 1 when files differ or would change and 2 on error.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/iwdgo/testingfiles"
+)
+
+// Exit status
+const (
+	exitOK      = 0
+	exitDiffers = 1
+	exitError   = 2
+)
+
+// errDiffers reports a difference or a change. Its message is already printed.
+var errDiffers = errors.New("differs")
+
+type command struct {
+	args string // usage of the arguments
+	run  func(fs *flag.FlagSet, args []string, stdout io.Writer) error
+}
+
+var commands = map[string]command{
+	"compare":    {"got want", compare},
+	"diff":       {"[-glob pattern] [-json] got want", diff},
+	"common":     {"[flags] dir glob common", common},
+	"supplement": {"[flags] dir glob base", supplement},
+	"recompose":  {"dir base supplement", recompose},
+	"update":     {"got want", update},
+	"prune":      {"[-n] -used file dir glob", prune},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("testingfiles", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	verbose := fs.Bool("v", false, "log the progress of the command")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: testingfiles [-v] command [flags] arguments")
+		fmt.Fprintln(stderr, "commands:")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Fprintf(stderr, "\t%s %s\n", name, commands[name].args)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+	name := fs.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "testingfiles: unknown command %s\n", name)
+		fs.Usage()
+		return exitError
+	}
+	log.SetOutput(io.Discard)
+	if *verbose {
+		log.SetOutput(stderr)
+	}
+	defer log.SetOutput(os.Stderr)
+
+	cfs := flag.NewFlagSet(name, flag.ContinueOnError)
+	cfs.SetOutput(stderr)
+	cfs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: testingfiles %s %s\n", name, cmd.args)
+		cfs.PrintDefaults()
+	}
+	err := cmd.run(cfs, fs.Args()[1:], stdout)
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errDiffers):
+		return exitDiffers
+	case errors.Is(err, flag.ErrHelp):
+		return exitError
+	}
+	fmt.Fprintf(stderr, "testingfiles %s: %v\n", name, err)
+	return exitError
+}
+
+// parse parses the flags and checks the number of arguments.
+func parse(fs *flag.FlagSet, args []string, n int) error {
+	if err := fs.Parse(args); err != nil {
+		return flag.ErrHelp
+	}
+	if fs.NArg() != n {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	return nil
+}
+
+func compare(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	if err := parse(fs, args, 2); err != nil {
+		return err
+	}
+	if err := existing(fs.Args()...); err != nil {
+		return err
+	}
+	if err := testingfiles.FileCompare(fs.Arg(0), fs.Arg(1)); err != nil {
+		fmt.Fprintf(stdout, "%s and %s differ: %v\n", fs.Arg(0), fs.Arg(1), err)
+		return errDiffers
+	}
+	return nil
+}
+
+func diff(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	glob := fs.String("glob", "*", "select the files of directories using `pattern`")
+	asJSON := fs.Bool("json", false, "print the differences of directories as JSON")
+	if err := parse(fs, args, 2); err != nil {
+		return err
+	}
+	got, want := fs.Arg(0), fs.Arg(1)
+	if fi, err := os.Stat(got); err == nil && fi.IsDir() {
+		d, err := testingfiles.DiffDirs(want, got, *glob)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			err = d.WriteJSON(stdout)
+		} else {
+			err = d.WriteText(stdout)
+		}
+		if err == nil && len(d.Files) != 0 {
+			err = errDiffers
+		}
+		return err
+	}
+	gotLines, err := testingfiles.ReadLines(got)
+	if err != nil {
+		return err
+	}
+	wantLines, err := testingfiles.ReadLines(want)
+	if err != nil {
+		return err
+	}
+	added, removed := testingfiles.DiffLines(wantLines, gotLines)
+	for _, l := range removed {
+		fmt.Fprintf(stdout, "-%s\n", l)
+	}
+	for _, l := range added {
+		fmt.Fprintf(stdout, "+%s\n", l)
+	}
+	if len(added) != 0 || len(removed) != 0 {
+		return errDiffers
+	}
+	return nil
+}
+
+// extractionFlags defines the flags of the extraction commands. The returned func returns the options
+// once flags are parsed.
+func extractionFlags(fs *flag.FlagSet) func() ([]testingfiles.Option, *testingfiles.Report, error) {
+	keepOrder := fs.Bool("keeporder", false, "keep the order of the lines of the files")
+	dedupe := fs.Bool("dedupe", false, "use a single copy of duplicated lines")
+	dryRun := fs.Bool("n", false, "print the files which would change without writing them")
+	dest := fs.String("dest", "", "write output files in `dir`")
+	mode := &fileMode{mode: 0644}
+	fs.Var(mode, "mode", "octal permissions of new files, or of all written files when set")
+	skipBlank := fs.Bool("skipblank", false, "ignore blank lines")
+	comments := fs.String("comments", "", "ignore lines starting with one of the comma separated `prefixes`")
+	trim := fs.Bool("trim", false, "remove white space around lines")
+	foldCase := fs.Bool("foldcase", false, "compare lines ignoring case")
+	header := fs.Bool("header", false, "write the leading comments of the files")
+	key := fs.String("key", "", "compare lines using the first submatch of `regexp`")
+	return func() ([]testingfiles.Option, *testingfiles.Report, error) {
+		r := &testingfiles.Report{}
+		opts := []testingfiles.Option{testingfiles.ReportTo(r)}
+		for _, o := range []struct {
+			set bool
+			opt testingfiles.Option
+		}{
+			{*keepOrder, testingfiles.KeepOrder()},
+			{*dedupe, testingfiles.Dedupe()},
+			{*dryRun, testingfiles.DryRun()},
+			{*dest != "", testingfiles.Destination(*dest)},
+			{*skipBlank, testingfiles.SkipBlank()},
+			{*comments != "", testingfiles.SkipComments(strings.Split(*comments, ",")...)},
+			{*trim, testingfiles.TrimSpace()},
+			{*foldCase, testingfiles.FoldCase()},
+			{*header, testingfiles.KeepHeader()},
+			{mode.set, testingfiles.FileMode(mode.mode)},
+		} {
+			if o.set {
+				opts = append(opts, o.opt)
+			}
+		}
+		if *key != "" {
+			re, err := regexp.Compile(*key)
+			if err != nil {
+				return nil, nil, err
+			}
+			opts = append(opts, testingfiles.KeyRegexp(re))
+		}
+		if !*dryRun {
+			r = nil
+		}
+		return opts, r, nil
+	}
+}
+
+// fileMode is a flag of permissions in octal.
+type fileMode struct {
+	mode os.FileMode
+	set  bool
+}
+
+func (m *fileMode) String() string {
+	return fmt.Sprintf("%#o", uint32(m.mode))
+}
+
+func (m *fileMode) Set(s string) error {
+	v, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return err
+	}
+	m.mode, m.set = os.FileMode(v).Perm(), true
+	return nil
+}
+
+// changed prints the files of a dry run which would change. errDiffers is returned if any.
+func changed(r *testingfiles.Report, stdout io.Writer) error {
+	if r == nil || len(r.Changed) == 0 {
+		return nil
+	}
+	for _, f := range r.Changed {
+		fmt.Fprintln(stdout, f)
+	}
+	return errDiffers
+}
+
+func common(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	options := extractionFlags(fs)
+	if err := parse(fs, args, 3); err != nil {
+		return err
+	}
+	opts, r, err := options()
+	if err != nil {
+		return err
+	}
+	if err = testingfiles.ExtractCommon(fs.Arg(0), fs.Arg(1), fs.Arg(2), opts...); err != nil {
+		return err
+	}
+	return changed(r, stdout)
+}
+
+func supplement(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	options := extractionFlags(fs)
+	verify := fs.Bool("verify", false, "write no file unless every file is rebuilt from its supplement")
+	suffix := fs.String("suffix", "", "write supplements to the name of the file followed by `suffix`")
+	if err := parse(fs, args, 3); err != nil {
+		return err
+	}
+	opts, r, err := options()
+	if err != nil {
+		return err
+	}
+	if *verify {
+		opts = append(opts, testingfiles.VerifySupplements())
+	}
+	if *suffix != "" {
+		opts = append(opts, testingfiles.SupplementName(func(name string) string {
+			return name + *suffix
+		}))
+	}
+	if err = testingfiles.CreateSupplements(fs.Arg(0), fs.Arg(1), fs.Arg(2), opts...); err != nil {
+		return err
+	}
+	return changed(r, stdout)
+}
+
+func recompose(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	if err := parse(fs, args, 3); err != nil {
+		return err
+	}
+	lines, err := testingfiles.RecomposeFiles(fs.Arg(0), fs.Arg(1), fs.Arg(2))
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		fmt.Fprintln(stdout, l)
+	}
+	return nil
+}
+
+func update(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	if err := parse(fs, args, 2); err != nil {
+		return err
+	}
+	got, want := fs.Arg(0), fs.Arg(1)
+	if err := existing(got); err != nil {
+		return err
+	}
+	if _, err := os.Stat(want); err == nil && testingfiles.FileCompare(got, want) == nil {
+		return nil
+	}
+	content, err := os.ReadFile(got)
+	if err != nil {
+		return err
+	}
+	if err = testingfiles.WriteFile(want, content); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s updated\n", want)
+	return nil
+}
+
+func prune(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	dryRun := fs.Bool("n", false, "print the unused golden files without removing them")
+	used := fs.String("used", "", "read the used golden files from `file` holding one name by line (required)")
+	if err := parse(fs, args, 2); err != nil {
+		return err
+	}
+	// Without the golden files used by tests, every file would be an orphan
+	if *used == "" {
+		return errors.New("-used is required")
+	}
+	names, err := testingfiles.ReadLines(*used, testingfiles.SkipBlank())
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("%s lists no golden file", *used)
+	}
+	testingfiles.UseGoldens(names...)
+	var orphans []string
+	if *dryRun {
+		orphans, err = testingfiles.Orphans(fs.Arg(0), fs.Arg(1))
+	} else {
+		orphans, err = testingfiles.RemoveOrphans(fs.Arg(0), fs.Arg(1))
+	}
+	for _, f := range orphans {
+		fmt.Fprintln(stdout, f)
+	}
+	if err == nil && *dryRun && len(orphans) != 0 {
+		return errDiffers
+	}
+	return err
+}
+
+// existing returns an error if one of the files does not exist.
+func existing(names ...string) error {
+	for _, n := range names {
+		if _, err := os.Stat(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testdata is the directory of the test files of the module.
+const testdata = "../../testdata"
+
+// runArgs runs the command line and returns its exit status and its output.
+func runArgs(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	var stdout, stderr strings.Builder
+	code := run(args, &stdout, &stderr)
+	if code == exitError {
+		t.Log(stderr.String())
+	}
+	return code, stdout.String()
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for f, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRun_usage(t *testing.T) {
+	for _, args := range [][]string{nil, {"unknown"}, {"compare", "one"}, {"common", "-unknown", "a", "b", "c"}} {
+		if code, _ := runArgs(t, args...); code != exitError {
+			t.Errorf("%q: got %d, want %d", args, code, exitError)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{"a": "line\n", "b": "line\n", "c": "other\n"})
+	for _, tc := range []struct {
+		got, want string
+		code      int
+	}{
+		{"a", "b", exitOK},
+		{"a", "c", exitDiffers},
+		{"a", "missing", exitError},
+	} {
+		if code, _ := runArgs(t, "compare", filepath.Join(w, tc.got), filepath.Join(w, tc.want)); code != tc.code {
+			t.Errorf("%s %s: got %d, want %d", tc.got, tc.want, code, tc.code)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	code, out := runArgs(t, "diff", filepath.Join(testdata, "case_2.txt"), filepath.Join(testdata, "case_1.txt"))
+	if code != exitDiffers {
+		t.Errorf("got %d, want %d", code, exitDiffers)
+	}
+	want := "-pkg syscall, const BIOCGDLTLIST = 3221766779\n" +
+		"-pkg syscall, const BIOCGRTIMEOUT = 1074545262\n" +
+		"-pkg syscall, const BIOCSETF = 2148024935\n" +
+		"-pkg syscall, const BIOCSETWF = 2148024951\n" +
+		"-pkg syscall, const BIOCSRTIMEOUT = 2148287085\n" +
+		"+pkg syscall, const BIOCGDLTLIST = 3222291067\n" +
+		"+pkg syscall, const BIOCGRTIMEOUT = 1074807406\n" +
+		"+pkg syscall, const BIOCSETF = 2148549223\n"
+	if out != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+	f := filepath.Join(testdata, "case_1.txt")
+	if code, out = runArgs(t, "diff", f, f); code != exitOK || out != "" {
+		t.Errorf("got %d and %q, want %d", code, out, exitOK)
+	}
+}
+
+func TestCommonSupplementRecompose(t *testing.T) {
+	w := t.TempDir()
+	if err := os.CopyFS(w, os.DirFS(testdata)); err != nil {
+		t.Fatal(err)
+	}
+	if code, out := runArgs(t, "common", "-n", w, "case_*.txt", "common.txt"); code != exitDiffers ||
+		out != filepath.Join(w, "common.txt")+"\n" {
+		t.Errorf("dry run: got %d and %q", code, out)
+	}
+	if code, _ := runArgs(t, "common", w, "case_*.txt", "common.txt"); code != exitOK {
+		t.Errorf("got %d, want %d", code, exitOK)
+	}
+	if code, _ := runArgs(t, "common", "-n", w, "case_*.txt", "common.txt"); code != exitOK {
+		t.Errorf("unchanged: got %d, want %d", code, exitOK)
+	}
+	if code, _ := runArgs(t, "supplement", "-verify", "-suffix", ".supp", w, "case_*.txt", "common.txt"); code != exitOK {
+		t.Errorf("got %d, want %d", code, exitOK)
+	}
+	code, out := runArgs(t, "recompose", w, "common.txt", "case_2.txt.supp")
+	if code != exitOK {
+		t.Errorf("got %d, want %d", code, exitOK)
+	}
+	b, err := os.ReadFile(filepath.Join(testdata, "case_2.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != string(b) {
+		t.Errorf("got %q, want %q", out, b)
+	}
+	if code, _ := runArgs(t, "common", "-key", "(", w, "case_*.txt", "common.txt"); code != exitError {
+		t.Errorf("invalid key: got %d, want %d", code, exitError)
+	}
+}
+
+func TestUpdatePrune(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{"got": "new\n", "want.golden": "old\n", "unused.golden": "\n"})
+	got, want := filepath.Join(w, "got"), filepath.Join(w, "want.golden")
+	if code, out := runArgs(t, "update", got, want); code != exitOK || out != want+" updated\n" {
+		t.Errorf("got %d and %q", code, out)
+	}
+	if code, out := runArgs(t, "update", got, want); code != exitOK || out != "" {
+		t.Errorf("unchanged: got %d and %q", code, out)
+	}
+	if code, _ := runArgs(t, "compare", got, want); code != exitOK {
+		t.Errorf("got %d, want %d", code, exitOK)
+	}
+	used := filepath.Join(w, "used.txt")
+	writeFiles(t, w, map[string]string{"used.txt": want + "\n"})
+	unused := filepath.Join(w, "unused.golden")
+	if code, out := runArgs(t, "prune", "-n", "-used", used, w, "*.golden"); code != exitDiffers || out != unused+"\n" {
+		t.Errorf("dry run: got %d and %q", code, out)
+	}
+	if code, _ := runArgs(t, "prune", "-used", used, w, "*.golden"); code != exitOK {
+		t.Errorf("got %d, want %d", code, exitOK)
+	}
+	if _, err := os.Stat(unused); err == nil {
+		t.Errorf("%s is not removed", unused)
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Error(err)
+	}
+}
+
+// Without the list of used golden files, nothing is removed
+func TestPrune_used(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{"a.golden": "a\n", "b.golden": "b\n", "used.txt": "\n"})
+	if code, _ := runArgs(t, "prune", w, "*.golden"); code != exitError {
+		t.Errorf("got %d, want %d", code, exitError)
+	}
+	if code, _ := runArgs(t, "prune", "-used", filepath.Join(w, "used.txt"), w, "*.golden"); code != exitError {
+		t.Errorf("empty list: got %d, want %d", code, exitError)
+	}
+	for _, f := range []string{"a.golden", "b.golden"} {
+		if _, err := os.Stat(filepath.Join(w, f)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestFileModeFlag(t *testing.T) {
+	var stderr strings.Builder
+	if code := run([]string{"common", "-h"}, io.Discard, &stderr); code != exitError {
+		t.Errorf("got %d, want %d", code, exitError)
+	}
+	if !strings.Contains(stderr.String(), "(default 0644)") {
+		t.Errorf("default mode is not octal in %s", stderr.String())
+	}
+	m := &fileMode{}
+	if err := m.Set("0600"); err != nil || m.mode != 0600 || !m.set {
+		t.Errorf("got %v, %v, %v", m.mode, m.set, err)
+	}
+	if err := m.Set("9"); err == nil {
+		t.Error("invalid mode is accepted")
+	}
+}
+
+func TestCommon_mode(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{"z_a.txt": "a\nb\n", "z_b.txt": "a\nc\n"})
+	for _, tc := range []struct {
+		args []string
+		want os.FileMode
+	}{
+		{nil, 0644},
+		{[]string{"-mode", "600"}, 0600},
+	} {
+		common := filepath.Join(w, "common.txt")
+		if err := os.RemoveAll(common); err != nil {
+			t.Fatal(err)
+		}
+		args := append(append([]string{"common"}, tc.args...), w, "z_*.txt", "common.txt")
+		if code, _ := runArgs(t, args...); code != exitOK {
+			t.Fatalf("%q: got %d, want %d", tc.args, code, exitOK)
+		}
+		fi, err := os.Stat(common)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fi.Mode().Perm(); got != tc.want {
+			t.Errorf("%q: got %#o, want %#o", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestDiff_dirs(t *testing.T) {
+	old, cur := t.TempDir(), t.TempDir()
+	writeFiles(t, old, map[string]string{"a.txt": "x\ny\n", "b.txt": "z\n"})
+	writeFiles(t, cur, map[string]string{"a.txt": "x\nw\n", "c.txt": "z\n"})
+	code, out := runArgs(t, "diff", "-glob", "*.txt", cur, old)
+	if code != exitDiffers {
+		t.Errorf("got %d, want %d", code, exitDiffers)
+	}
+	want := "# a.txt\n-y\n+w\n# b.txt\n-z\n# c.txt\n+z\n# all\n-y\n+w\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+	if code, out = runArgs(t, "diff", "-json", cur, cur); code != exitOK || !strings.Contains(out, `"files": null`) {
+		t.Errorf("got %d and %q", code, out)
+	}
+}
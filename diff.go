@@ -0,0 +1,149 @@
+package testingfiles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DiffLines returns the sorted lines of after which are not in before, and those of before which are not
+// in after. A line found n times in before and m times in after is added or removed |m-n| times.
+func DiffLines(before, after []string) (added, removed []string) {
+	before, after = slices.Clone(before), slices.Clone(after)
+	slices.Sort(before)
+	slices.Sort(after)
+	return diffSorted(before, after)
+}
+
+// diffSorted merges the sorted lines before and after in a single pass. It returns the lines only in after
+// and those only in before.
+func diffSorted(before, after []string) (added, removed []string) {
+	i, j := 0, 0
+	for i < len(before) && j < len(after) {
+		switch {
+		case before[i] < after[j]:
+			removed = append(removed, before[i])
+			i++
+		case after[j] < before[i]:
+			added = append(added, after[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return append(added, after[j:]...), append(removed, before[i:]...)
+}
+
+// FileDiff holds the lines added to and removed from a file.
+type FileDiff struct {
+	Name    string   `json:"name"` // name relative to the directories
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// DirDiff holds the lines added to and removed from the files of a directory, like two releases of
+// API files.
+type DirDiff struct {
+	Files   []FileDiff `json:"files"`   // files which differ sorted by name
+	Added   []string   `json:"added"`   // lines of no old file which are in a new file
+	Removed []string   `json:"removed"` // lines of an old file which are in no new file
+}
+
+// DiffDirs compares the files of oldDir and newDir selected by globf. A file missing from a directory
+// has no lines. Lines are filtered by SkipBlank, SkipComments and TrimSpace.
+// fs.ErrNotExist is returned when no file is selected.
+func DiffDirs(oldDir, newDir, globf string, opts ...Option) (*DirDiff, error) {
+	o := newOptions(opts)
+	names := make(map[string]bool)
+	for _, dir := range []string{oldDir, newDir} {
+		fl, err := filepath.Glob(filepath.Join(dir, globf))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fl {
+			if fi, err := os.Stat(f); err == nil && !fi.IsDir() {
+				names[filepath.Base(f)] = true
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	d := &DirDiff{}
+	var oldAll, newAll []string
+	for name := range names {
+		before, err := o.diffLines(filepath.Join(oldDir, name))
+		if err != nil {
+			return nil, err
+		}
+		after, err := o.diffLines(filepath.Join(newDir, name))
+		if err != nil {
+			return nil, err
+		}
+		oldAll, newAll = append(oldAll, before...), append(newAll, after...)
+		added, removed := diffSorted(before, after)
+		if len(added) != 0 || len(removed) != 0 {
+			d.Files = append(d.Files, FileDiff{Name: name, Added: added, Removed: removed})
+		}
+	}
+	slices.SortFunc(d.Files, func(a, b FileDiff) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	d.Added, d.Removed = diffSorted(sortedSet(oldAll), sortedSet(newAll))
+	return d, nil
+}
+
+// diffLines returns the sorted lines of filename kept by the filters. A missing file has no lines.
+func (o *options) diffLines(filename string) ([]string, error) {
+	lines, err := o.readLines(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lines = o.filter(lines)
+	slices.Sort(lines)
+	return lines, nil
+}
+
+// WriteText writes the removed lines prefixed by - and the added lines prefixed by + of every file after
+// a # name line, then those of all files after a # all line.
+func (d *DirDiff) WriteText(w io.Writer) error {
+	write := func(title string, added, removed []string) error {
+		if _, err := fmt.Fprintf(w, "# %s\n", title); err != nil {
+			return err
+		}
+		for _, l := range removed {
+			if _, err := fmt.Fprintf(w, "-%s\n", l); err != nil {
+				return err
+			}
+		}
+		for _, l := range added {
+			if _, err := fmt.Fprintf(w, "+%s\n", l); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, f := range d.Files {
+		if err := write(f.Name, f.Added, f.Removed); err != nil {
+			return err
+		}
+	}
+	return write("all", d.Added, d.Removed)
+}
+
+// WriteJSON writes the differences as indented JSON.
+func (d *DirDiff) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(d)
+}
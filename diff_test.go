@@ -0,0 +1,69 @@
+package testingfiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	for _, tc := range []struct {
+		before, after  []string
+		added, removed []string
+	}{
+		{nil, nil, nil, nil},
+		{[]string{"b", "a"}, []string{"a", "b"}, nil, nil},
+		{[]string{"a"}, []string{"b", "a", "c"}, []string{"b", "c"}, nil},
+		{[]string{"a", "a", "d"}, []string{"a", "c"}, []string{"c"}, []string{"a", "d"}},
+	} {
+		added, removed := DiffLines(tc.before, tc.after)
+		if !slices.Equal(added, tc.added) || !slices.Equal(removed, tc.removed) {
+			t.Errorf("%q, %q: got %q and %q, want %q and %q", tc.before, tc.after, added, removed, tc.added, tc.removed)
+		}
+	}
+}
+
+func TestDiffDirs(t *testing.T) {
+	old, cur := t.TempDir(), t.TempDir()
+	writeFiles(t, old, map[string]string{"go1.1.txt": "pkg a, func F()\npkg a, func G()\n", "go1.2.txt": "pkg b, const C = 1\n"})
+	writeFiles(t, cur, map[string]string{"go1.1.txt": "pkg a, func F()\n# comment\npkg a, func H()\n",
+		"go1.2.txt": "pkg b, const C = 1\n", "go1.3.txt": "pkg a, func G()\n"})
+	d, err := DiffDirs(old, cur, "go1.*.txt", SkipComments("#"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DirDiff{
+		Files: []FileDiff{
+			{Name: "go1.1.txt", Added: []string{"pkg a, func H()"}, Removed: []string{"pkg a, func G()"}},
+			{Name: "go1.3.txt", Added: []string{"pkg a, func G()"}},
+		},
+		// G moved to another file
+		Added: []string{"pkg a, func H()"},
+	}
+	var b bytes.Buffer
+	if err = d.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	var got *DirDiff
+	if err = json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	var text bytes.Buffer
+	if err = d.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	wantText := "# go1.1.txt\n-pkg a, func G()\n+pkg a, func H()\n# go1.3.txt\n+pkg a, func G()\n# all\n+pkg a, func H()\n"
+	if text.String() != wantText {
+		t.Errorf("got %q, want %q", text.String(), wantText)
+	}
+	if _, err = DiffDirs(old, cur, "none"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+}
@@ -0,0 +1,282 @@
+package testingfiles
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+)
+
+// StripBOM removes the UTF-8 byte order mark at the start of contents before they are compared.
+func StripBOM() Option {
+	return func(o *options) {
+		o.stripBOM = true
+	}
+}
+
+// DecodeUTF16 decodes contents starting with a UTF-16 byte order mark to UTF-8 before they are compared.
+// Other contents are unchanged.
+func DecodeUTF16() Option {
+	return func(o *options) {
+		o.decodeUTF16 = true
+	}
+}
+
+// Normalize applies normalize to every line of contents, without its new line, before they are compared.
+// It is meant for Unicode normalization, like norm.NFC.Bytes of golang.org/x/text/unicode/norm.
+func Normalize(normalize func(line []byte) []byte) Option {
+	return func(o *options) {
+		o.normalize = normalize
+	}
+}
+
+// text is a content prepared for comparison. off holds the offset in the raw content of every byte of data
+// and the length of the raw content.
+type text struct {
+	data  []byte
+	off   []int
+	raw   []byte
+	order binary.ByteOrder // byte order of a UTF-16 raw content, nil for UTF-8
+}
+
+func newText(raw []byte) text {
+	off := make([]int, len(raw)+1)
+	for i := range off {
+		off[i] = i
+	}
+	return text{data: raw, off: off, raw: raw}
+}
+
+// decodes reports whether contents are decoded before comparison.
+func (o *options) decodes() bool {
+	return o.stripBOM || o.decodeUTF16 || o.normalize != nil
+}
+
+// compareOptions returns an error wrapping errors.ErrUnsupported which lists the options set which
+// compare functions do not support.
+func (o *options) compareOptions() error {
+	var unsupported []string
+	for _, opt := range []struct {
+		set  bool
+		name string
+	}{
+		{o.mode != 0, "FileMode"},
+		{o.verify, "VerifySupplements"},
+		{o.rename != nil, "SupplementName"},
+		{o.keepOrder, "KeepOrder"},
+		{o.dedupe || o.multiset, "Dedupe or Multiset"},
+		{o.memoryLimit != 0, "MemoryLimit"},
+		{o.workers != 0, "Workers"},
+		{o.key != nil, "Key"},
+		{o.dryRun, "DryRun"},
+		{o.report != nil, "ReportTo"},
+		{o.dest != "", "Destination"},
+		{o.skipBlank || len(o.comments) != 0 || o.trimSpace || o.foldCase || o.keepHeader, "line filters"},
+	} {
+		if opt.set {
+			unsupported = append(unsupported, opt.name)
+		}
+	}
+	if len(unsupported) != 0 {
+		return fmt.Errorf("%w: %s when comparing", errors.ErrUnsupported, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// transforms reports whether contents are transformed before comparison.
+func (o *options) transforms() bool {
+	return o.decodes() || o.spaces() || o.unordered
+}
+
+// prepare decodes the raw content and removes the white space ignored by the comparison.
+func (o *options) prepare(raw []byte) text {
+	t := o.decode(raw)
+	if o.spaces() {
+		t = t.mapSpace(o)
+	}
+	return t
+}
+
+// decode decodes the raw content as set by the options.
+func (o *options) decode(raw []byte) text {
+	t := newText(raw)
+	if o.decodeUTF16 {
+		t = t.decodeUTF16()
+	}
+	if o.stripBOM && bytes.HasPrefix(t.data, bomUTF8) {
+		t.data, t.off = t.data[len(bomUTF8):], t.off[len(bomUTF8):]
+	}
+	if o.normalize != nil {
+		t = t.mapLines(o.normalize)
+	}
+	return t
+}
+
+// decodeUTF16 decodes the content if it starts with a UTF-16 byte order mark which is removed.
+func (t text) decodeUTF16() text {
+	switch {
+	case bytes.HasPrefix(t.data, bomUTF16BE):
+		t.order = binary.BigEndian
+	case bytes.HasPrefix(t.data, bomUTF16LE):
+		t.order = binary.LittleEndian
+	default:
+		return t
+	}
+	d := text{raw: t.raw, order: t.order}
+	for i := len(bomUTF16BE); i < len(t.data); {
+		r, n := utf8.RuneError, len(t.data)-i
+		if n >= 2 {
+			r, n = rune(t.order.Uint16(t.data[i:])), 2
+			if utf16.IsSurrogate(r) && i+4 <= len(t.data) {
+				if dr := utf16.DecodeRune(r, rune(t.order.Uint16(t.data[i+2:]))); dr != utf8.RuneError {
+					r, n = dr, 4
+				}
+			}
+		}
+		for range utf8.AppendRune(nil, r) {
+			d.off = append(d.off, t.off[i])
+		}
+		d.data = utf8.AppendRune(d.data, r)
+		i += n
+	}
+	d.off = append(d.off, t.off[len(t.data)])
+	return d
+}
+
+// mapLines applies f to every line without its new line. Bytes of a changed line after its common prefix
+// with the result are located at the first changed byte.
+func (t text) mapLines(f func(line []byte) []byte) text {
+	m := text{raw: t.raw, order: t.order}
+	for start := 0; start < len(t.data); {
+		end := bytes.IndexByte(t.data[start:], '\n')
+		if end == -1 {
+			end = len(t.data)
+		} else {
+			end += start
+		}
+		line := t.data[start:end]
+		mapped := f(bytes.Clone(line))
+		p := 0
+		for p < len(line) && p < len(mapped) && line[p] == mapped[p] {
+			p++
+		}
+		m.data = append(m.data, mapped...)
+		m.off = append(m.off, t.off[start:start+p]...)
+		for range mapped[p:] {
+			m.off = append(m.off, t.off[start+p])
+		}
+		if end < len(t.data) {
+			m.data = append(m.data, '\n')
+			m.off = append(m.off, t.off[end])
+		}
+		start = end + 1
+	}
+	m.off = append(m.off, t.off[len(t.data)])
+	return m
+}
+
+// position returns the line and the column, starting at 1, of the raw offset.
+// Columns count bytes, or 16-bit units of a UTF-16 content.
+func (t text) position(offset int) (line, column int) {
+	unit := 1
+	if t.order != nil {
+		unit = 2
+	}
+	line, column = 1, 1
+	for i := 0; i+unit <= offset && i+unit <= len(t.raw); i += unit {
+		c := rune(t.raw[i])
+		if t.order != nil {
+			c = rune(t.order.Uint16(t.raw[i:]))
+		}
+		if c == '\n' {
+			line, column = line+1, 1
+			continue
+		}
+		column++
+	}
+	return line, column
+}
+
+// lineStart returns the index in data of the start of the line k which starts at 0.
+func (t text) lineStart(k int) int {
+	i := 0
+	for ; k > 0; k-- {
+		i += bytes.IndexByte(t.data[i:], '\n') + 1
+	}
+	return i
+}
+
+// Position locates a byte in a raw content. Line and Column start at 1.
+type Position struct {
+	Offset int64 `json:"offset"`
+	Line   int   `json:"line"`
+	Column int   `json:"column"`
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d (line %d, column %d)", p.Offset, p.Line, p.Column)
+}
+
+// MismatchError reports the first difference of contents compared using options.
+// Positions are the ones of the raw contents before they are transformed for comparison.
+type MismatchError struct {
+	Got, Want         Position
+	GotText, WantText string // differing decoded character, empty at the end of a content
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("got %q at %v, want %q at %v", e.GotText, e.Got, e.WantText, e.Want)
+}
+
+// compareTexts returns a *MismatchError at the first difference of got and want, or nil.
+func compareTexts(got, want text) error {
+	i := 0
+	for i < len(got.data) && i < len(want.data) && got.data[i] == want.data[i] {
+		i++
+	}
+	if i == len(got.data) && i == len(want.data) {
+		return nil
+	}
+	// Start of the differing character
+	for i > 0 && (i < len(got.data) && !utf8.RuneStart(got.data[i]) ||
+		i < len(want.data) && !utf8.RuneStart(want.data[i])) {
+		i--
+	}
+	e := &MismatchError{}
+	for _, s := range []struct {
+		t   text
+		pos *Position
+		c   *string
+	}{{got, &e.Got, &e.GotText}, {want, &e.Want, &e.WantText}} {
+		off := s.t.off[i]
+		s.pos.Offset = int64(off)
+		s.pos.Line, s.pos.Column = s.t.position(off)
+		if i < len(s.t.data) {
+			_, n := utf8.DecodeRune(s.t.data[i:])
+			*s.c = string(s.t.data[i : i+n])
+		}
+	}
+	return e
+}
+
+// readLines returns the lines of filename decoded like compare functions do.
+func (o *options) readLines(filename string) ([]string, error) {
+	if !o.decodes() {
+		return readLines(filename)
+	}
+	bs, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(string(o.decode(bs).data)), nil
+}
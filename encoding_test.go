@@ -0,0 +1,141 @@
+package testingfiles
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16LE encodes s in UTF-16 little endian with a byte order mark.
+func utf16LE(s string) []byte {
+	b := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return b
+}
+
+// compose replaces e followed by a combining acute accent by é.
+func compose(line []byte) []byte {
+	return bytes.ReplaceAll(line, []byte("e\u0301"), []byte("é"))
+}
+
+func TestFileCompare_encoding(t *testing.T) {
+	w := t.TempDir()
+	want := filepath.Join(w, "want")
+	if err := os.WriteFile(want, []byte("café 😀\nline\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		got  []byte
+		opts []Option
+	}{
+		{"bom", append([]byte{0xEF, 0xBB, 0xBF}, "café 😀\nline\n"...), []Option{StripBOM()}},
+		{"utf16", utf16LE("café 😀\nline\n"), []Option{DecodeUTF16()}},
+		{"utf16 bom", utf16LE("\uFEFFcafé 😀\nline\n"), []Option{DecodeUTF16(), StripBOM()}},
+		{"nfd", []byte("cafe\u0301 😀\nline\n"), []Option{Normalize(compose)}},
+	} {
+		got := filepath.Join(w, "got")
+		if err := os.WriteFile(got, tc.got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := FileCompare(got, want); err == nil {
+			t.Errorf("%s: unexpected success without option", tc.name)
+		}
+		if err := FileCompare(got, want, tc.opts...); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if err := BufferCompare(bytes.NewBuffer(tc.got), want, tc.opts...); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if err := ReadCloserCompare(io.NopCloser(bytes.NewReader(tc.got)), want, tc.opts...); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+}
+
+func TestFileCompare_mismatch(t *testing.T) {
+	w := t.TempDir()
+	want := filepath.Join(w, "want")
+	if err := os.WriteFile(want, []byte("first\nsecond line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		got  []byte
+		opts []Option
+		pos  Position
+		text string
+	}{
+		{"bom", append([]byte{0xEF, 0xBB, 0xBF}, "first\nsecond lime\n"...), []Option{StripBOM()},
+			Position{Offset: 18, Line: 2, Column: 10}, "m"},
+		{"utf16", utf16LE("first\nsecond lime\n"), []Option{DecodeUTF16()},
+			Position{Offset: 32, Line: 2, Column: 10}, "m"},
+		{"short", []byte("first\nsecond"), []Option{StripBOM()},
+			Position{Offset: 12, Line: 2, Column: 7}, ""},
+		{"nfd", []byte("first\nsecone\u0301\n"), []Option{Normalize(compose)},
+			Position{Offset: 11, Line: 2, Column: 6}, "é"},
+	} {
+		err := BufferCompare(bytes.NewBuffer(tc.got), want, tc.opts...)
+		var m *MismatchError
+		if !errors.As(err, &m) {
+			t.Fatalf("%s: got %v, want a *MismatchError", tc.name, err)
+		}
+		if m.Got != tc.pos || m.GotText != tc.text {
+			t.Errorf("%s: got %v %q, want %v %q", tc.name, m.Got, m.GotText, tc.pos, tc.text)
+		}
+		if !strings.Contains(err.Error(), tc.pos.String()) {
+			t.Errorf("%s: %v does not locate %v", tc.name, err, tc.pos)
+		}
+	}
+}
+
+func TestExtractCommon_encoding(t *testing.T) {
+	w := t.TempDir()
+	for f, content := range map[string][]byte{
+		"case_1.txt": append([]byte{0xEF, 0xBB, 0xBF}, "a\nb\n"...),
+		"case_2.txt": utf16LE("b\na\nc\n"),
+	} {
+		if err := os.WriteFile(filepath.Join(w, f), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := Common(w, globf, StripBOM(), DecodeUTF16())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFileCompare_unsupported(t *testing.T) {
+	want := filepath.Join(t.TempDir(), "want")
+	if err := os.WriteFile(want, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, opt := range []Option{Destination("dest"), Key(strings.ToUpper), SkipBlank(), DryRun()} {
+		if err := FileCompare(want, want, opt); !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("got %v, want %v", err, errors.ErrUnsupported)
+		}
+		if err := BufferCompare(bytes.NewBufferString("a"), want, opt); !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("got %v, want %v", err, errors.ErrUnsupported)
+		}
+		err := ReadCloserCompare(io.NopCloser(strings.NewReader("a")), want, opt)
+		if !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("got %v, want %v", err, errors.ErrUnsupported)
+		}
+	}
+	// Options empty the buffer
+	b := bytes.NewBufferString("a")
+	if err := BufferCompare(b, want, StripBOM()); err != nil || b.Len() != 0 {
+		t.Errorf("got %v with %d bytes left, want an empty buffer", err, b.Len())
+	}
+}
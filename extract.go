@@ -3,125 +3,348 @@ package testingfiles
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
+	"sync"
 )
 
 // ExtractCommon creates a file commonf containing all lines found in all files selected by globf filter.
 // It returns an error if the intersection is empty.
-func ExtractCommon(contextFilesPath, globf, commonf string) error {
-	fl, err := filepath.Glob(filepath.Join(contextFilesPath, globf))
+// Lines are sorted unless KeepOrder is used. With Key or KeyRegexp, lines are compared using their key.
+// commonf is written in contextFilesPath unless Destination is used.
+func ExtractCommon(contextFilesPath, globf, commonf string, opts ...Option) error {
+	o := newOptions(opts)
+	intersec, err := o.common(contextFilesPath, globf)
 	if err != nil {
 		return err
 	}
-	if len(fl) == 0 {
-		return fs.ErrNotExist
+	if err = o.output(o.destination(contextFilesPath, commonf), intersec); err != nil {
+		return err
 	}
-	intersec, err := requiredFeatures(fl[0])
+	log.Printf("intersection has %v line(s) written to %s", len(intersec), commonf)
+	return nil
+}
+
+// ExtractCommonTo writes the lines which ExtractCommon would write to w.
+func ExtractCommonTo(w io.Writer, contextFilesPath, globf string, opts ...Option) error {
+	intersec, err := Common(contextFilesPath, globf, opts...)
 	if err != nil {
 		return err
 	}
-	fl = fl[1:]
-	i := 0
-	for _, f := range fl {
-		flines, err1 := requiredFeatures(f)
-		if err1 != nil {
-			return err1
-		}
-		for {
-			if intersec[i] == flines[0] {
-				i++
-				flines = flines[1:]
-			} else if intersec[i] < flines[0] {
-				// feature is unknown in checked file and must be removed from intersection
-				intersec = slices.Delete(intersec, i, i+1)
-			} else {
-				// skip feature unknown to intersection
-				flines = flines[1:]
+	_, err = io.WriteString(w, strings.Join(intersec, "\n"))
+	return err
+}
+
+// Common returns the lines which ExtractCommon would write.
+func Common(contextFilesPath, globf string, opts ...Option) ([]string, error) {
+	return newOptions(opts).common(contextFilesPath, globf)
+}
+
+// common returns the intersection of the files of contextFilesPath selected by globf and reports it.
+func (o *options) common(contextFilesPath, globf string) ([]string, error) {
+	fl, err := filepath.Glob(filepath.Join(contextFilesPath, globf))
+	if err != nil {
+		return nil, err
+	}
+	if len(fl) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	lists, err := o.loadFeatures(fl)
+	if err != nil {
+		return nil, err
+	}
+	var intersec []string
+	switch {
+	case o.keyed():
+		intersec = keyedIntersection(lists, o)
+	case o.keepOrder:
+		intersec = orderedIntersection(lists)
+	default:
+		intersec = sortedIntersection(lists)
+	}
+	r := o.newReport(fl, lists)
+	if len(intersec) == 0 {
+		f := fl[o.emptiedBy(lists)]
+		log.Printf("intersection is empty as %s has nothing in common with other files", filepath.Base(f))
+		if r != nil {
+			r.EmptiedBy = f
+		}
+		return nil, os.ErrNotExist
+	}
+	if r != nil {
+		r.Common = intersec
+		keys := o.keySet(intersec)
+		for i, lines := range lists {
+			for _, l := range lines {
+				if _, found := slices.BinarySearch(keys, o.lineKey(l)); !found {
+					r.Files[i].Removed++
+				}
+			}
+		}
+	}
+	return o.withHeader(fl[0], intersec)
+}
+
+// destination returns the name of the output file name of dir, which is replaced by the directory
+// set by Destination if any.
+func (o *options) destination(dir, name string) string {
+	if o.dest != "" {
+		dir = o.dest
+	}
+	return filepath.Join(dir, name)
+}
+
+// sortedIntersection returns the sorted lines found in all sorted lists by merging them in a single pass.
+// A line found n times in every list is kept n times.
+func sortedIntersection(lists [][]string) []string {
+	var intersec []string
+	heads := make([]int, len(lists))
+merge:
+	for {
+		// Every list skips the lines below the largest current line which is kept if all lists hold it
+		top := lists[0][heads[0]]
+		for i, h := range heads {
+			if lists[i][h] > top {
+				top = lists[i][h]
+			}
+		}
+		found := true
+		for i, l := range lists {
+			for heads[i] < len(l) && l[heads[i]] < top {
+				heads[i]++
 			}
-			if i == len(intersec) {
-				break
-			} else if len(flines) == 0 {
-				intersec = intersec[:i]
-				break
+			if heads[i] == len(l) {
+				break merge
 			}
+			found = found && l[heads[i]] == top
 		}
-		if len(intersec) == 0 {
-			log.Printf("intersection is empty as %s has nothing in common with other files", filepath.Base(f))
-			return os.ErrNotExist
+		if !found {
+			continue
+		}
+		intersec = append(intersec, top)
+		for i, l := range lists {
+			if heads[i]++; heads[i] == len(l) {
+				break merge
+			}
 		}
-		i = 0
 	}
-	if err = os.WriteFile(filepath.Join(contextFilesPath, commonf),
-		[]byte(strings.Join(intersec, "\n")), os.ModePerm); err != nil {
+	return intersec
+}
+
+// orderedIntersection returns the lines of the first list found in all lists in the order of the first list.
+// Membership is checked using the counts of the lines of each list. A line is kept as many times as the list
+// holding the fewest copies of it, like sortedIntersection.
+func orderedIntersection(lists [][]string) []string {
+	intersec := slices.Clone(lists[0])
+	counts := lineCounts(intersec)
+	for _, lines := range lists[1:] {
+		fcounts := lineCounts(lines)
+		for l, n := range counts {
+			if fcounts[l] < n {
+				counts[l] = fcounts[l]
+			}
+		}
+	}
+	return slices.DeleteFunc(intersec, func(l string) bool {
+		counts[l]--
+		return counts[l] < 0
+	})
+}
+
+// CreateSupplements removes all lines of a baselinef file from all files in globf.
+// Supplements are computed for all files before any file is written.
+// Files are overwritten unless SupplementName or Destination is used. With VerifySupplements, no file is written
+// unless every supplement recomposes its original file with the baseline.
+// With Key or KeyRegexp, a line is removed when the baseline has its key and recomposition compares keys.
+func CreateSupplements(contextFilesPath, globf, baselinef string, opts ...Option) error {
+	o := newOptions(opts)
+	fl, supplements, err := o.supplements(contextFilesPath, globf, baselinef)
+	if err != nil {
 		return err
 	}
-	log.Printf("intersection has %v line(s) written to %s", len(intersec), commonf)
+	for i, f := range fl {
+		if o.rename != nil {
+			f = o.rename(f)
+		}
+		f = o.destination(filepath.Dir(f), filepath.Base(f))
+		if err = o.output(f, supplements[i]); err != nil {
+			return err
+		}
+		log.Printf("%s has %v line(s)", f, len(supplements[i]))
+	}
 	return nil
 }
 
-// CreateSupplements removes all lines of a baselinef file from all files in globf
-func CreateSupplements(contextFilesPath, globf, baselinef string) error {
+// Supplements returns the lines which CreateSupplements would write by input file.
+func Supplements(contextFilesPath, globf, baselinef string, opts ...Option) (map[string][]string, error) {
+	fl, supplements, err := newOptions(opts).supplements(contextFilesPath, globf, baselinef)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string][]string, len(fl))
+	for i, f := range fl {
+		m[f] = supplements[i]
+	}
+	return m, nil
+}
+
+// supplements returns the files selected by globf and their supplements, and reports them.
+func (o *options) supplements(contextFilesPath, globf, baselinef string) ([]string, [][]string, error) {
 	fl, err := filepath.Glob(filepath.Join(contextFilesPath, globf))
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	if len(fl) == 0 {
-		return fs.ErrNotExist
+		return nil, nil, fs.ErrNotExist
 	}
-	baseline, err := requiredFeatures(filepath.Join(contextFilesPath, baselinef))
+	baseline, err := o.orderedFeatures(filepath.Join(contextFilesPath, baselinef))
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	base := baseline
-	for _, f := range fl {
-		flines, err1 := requiredFeatures(f)
-		if err1 != nil {
-			return err1
-		}
-		var supplement []string
-		for {
-			if base[0] == flines[0] {
-				base = base[1:]
-				flines = flines[1:]
-			} else if base[0] > flines[0] {
-				supplement = append(supplement, flines[0])
-				flines = flines[1:]
-				if len(flines) == 0 {
-					return errors.New(fmt.Sprintf("common feature is missing: %s", base[0]))
-				}
-			} else {
-				return errors.New(fmt.Sprintf("common feature is missing: %s", base[0]))
-			}
-			if len(base) == 0 {
-				break
-			}
+	slices.Sort(baseline)
+	if o.dedupe {
+		baseline = slices.Compact(baseline)
+	}
+	r := o.newReport(fl, nil)
+	if r != nil {
+		r.Common = baseline
+	}
+	supplements := make([][]string, len(fl))
+	var errs []error
+	for i, f := range fl {
+		var supplement, original []string
+		switch {
+		case o.keyed():
+			supplement, original, err = keyedSupplement(f, baseline, o)
+		case o.keepOrder:
+			supplement, original, err = orderedSupplement(f, baseline, o)
+		default:
+			supplement, original, err = sortedSupplement(f, baseline, o)
+		}
+		var missing *MissingLinesError
+		if errors.As(err, &missing) {
+			errs = append(errs, err)
+			continue
 		}
-		err = os.WriteFile(f, []byte(strings.Join(supplement, "\n")), os.ModePerm)
 		if err != nil {
-			return err
+			return nil, nil, err
+		}
+		if o.verify {
+			if err = o.verifySupplement(f, baseline, supplement); err != nil {
+				return nil, nil, fmt.Errorf("%s is not recomposed from %s and its supplement: %w", f, baselinef, err)
+			}
 		}
-		log.Printf("%s has %v line(s)", f, len(supplement))
-		base = baseline
+		if r != nil {
+			r.Files[i].Lines, r.Files[i].Removed = len(original), len(original)-len(supplement)
+		}
+		if supplements[i], err = o.withHeader(f, supplement); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(errs) != 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	return fl, supplements, nil
+}
+
+// sortedSupplement returns the sorted lines of f which are not in the sorted baseline, and the lines of f.
+// A line found n times in the baseline is removed n times.
+// It fails with a *MissingLinesError if lines of the baseline are missing from f.
+func sortedSupplement(f string, baseline []string, o *options) (supplement, original []string, err error) {
+	original, err = o.features(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	supplement, missing := diffSorted(baseline, original)
+	if len(missing) != 0 {
+		return nil, nil, &MissingLinesError{File: f, Lines: missing}
+	}
+	return supplement, original, nil
+}
+
+// orderedSupplement returns the lines of f which are not in the baseline in the order of f, and the lines of f.
+// A line found n times in the baseline is removed n times, like sortedSupplement.
+// It fails with a *MissingLinesError if lines of the baseline are missing from f.
+func orderedSupplement(f string, baseline []string, o *options) (supplement, original []string, err error) {
+	original, err = o.features(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	base, counts := lineCounts(baseline), lineCounts(original)
+	var missing []string
+	for _, l := range baseline {
+		if counts[l] < base[l] {
+			missing = append(missing, l)
+			counts[l]++
+		}
+	}
+	if len(missing) != 0 {
+		return nil, nil, &MissingLinesError{File: f, Lines: missing}
+	}
+	for _, l := range original {
+		if base[l] == 0 {
+			supplement = append(supplement, l)
+		} else {
+			base[l]--
+		}
+	}
+	return supplement, original, nil
+}
+
+// verifySupplement reads the file f again and checks that baseline and supplement recompose its lines
+// as multisets. Keys are compared as sets when lines are keyed and lines as sets with Dedupe.
+func (o *options) verifySupplement(f string, baseline, supplement []string) error {
+	lines, err := o.orderedFeatures(f)
+	if err != nil {
+		return err
+	}
+	recomposed := Recompose(baseline, supplement)
+	switch {
+	case o.keyed():
+		recomposed, lines = o.keySet(recomposed), o.keySet(lines)
+	case o.dedupe:
+		lines = sortedSet(lines)
+	default:
+		slices.Sort(lines)
+	}
+	if !slices.Equal(recomposed, lines) {
+		return errors.New("lines differ")
 	}
 	return nil
 }
 
-func requiredFeatures(filename string) ([]string, error) {
-	bs, err := os.ReadFile(filename)
+// Recompose returns the sorted lines of baseline and supplement which rebuilds the file
+// from which the supplement was created.
+func Recompose(baseline, supplement []string) []string {
+	lines := make([]string, 0, len(baseline)+len(supplement))
+	lines = append(append(lines, baseline...), supplement...)
+	slices.Sort(lines)
+	return lines
+}
+
+// RecomposeFiles returns the recomposed lines of the files baselinef and supplementf of contextFilesPath.
+func RecomposeFiles(contextFilesPath, baselinef, supplementf string) ([]string, error) {
+	baseline, err := readLines(filepath.Join(contextFilesPath, baselinef))
 	if err != nil {
 		return nil, err
 	}
-	bss := string(bs)
-	//  TODO CRLF line ending is not handled
-	lines := strings.Split(bss, "\n")
-	// A last empty line is removed as it is usually an artifact
-	if strings.TrimSpace(lines[len(lines)-1]) == "" {
-		lines = lines[:len(lines)-1]
+	supplement, err := readLines(filepath.Join(contextFilesPath, supplementf))
+	if err != nil {
+		return nil, err
+	}
+	return Recompose(baseline, supplement), nil
+}
+
+func requiredFeatures(filename string) ([]string, error) {
+	lines, err := readLines(filename)
+	if err != nil {
+		return nil, err
 	}
 	if len(lines) == 0 {
 		log.Printf("%s is empty", filename)
@@ -131,3 +354,149 @@ func requiredFeatures(filename string) ([]string, error) {
 	log.Printf("%s has %v lines", filename, len(lines))
 	return lines, nil
 }
+
+// orderedFeatures returns the decoded lines of filename in file order which are kept by the filters of the options.
+// Like requiredFeatures, it fails on an empty file.
+func (o *options) orderedFeatures(filename string) ([]string, error) {
+	lines, err := o.readLines(filename)
+	if err != nil {
+		return nil, err
+	}
+	lines = o.filter(lines)
+	if len(lines) == 0 {
+		log.Printf("%s is empty", filename)
+		return nil, fs.ErrNotExist
+	}
+	log.Printf("%s has %v lines", filename, len(lines))
+	return lines, nil
+}
+
+// features returns the lines of filename sorted, or in file order with KeepOrder.
+// With Dedupe, only the first copy of a line is kept.
+func (o *options) features(filename string) ([]string, error) {
+	lines, err := o.orderedFeatures(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !o.keepOrder {
+		slices.Sort(lines)
+	}
+	if !o.dedupe {
+		return lines, nil
+	}
+	seen := make(map[string]bool, len(lines))
+	return slices.DeleteFunc(lines, func(l string) bool {
+		if seen[l] {
+			return true
+		}
+		seen[l] = true
+		return false
+	}), nil
+}
+
+// loadFeatures returns the features of every file of fl in the same order. Files are read concurrently
+// by the number of workers set by Workers. Errors of all files are returned.
+func (o *options) loadFeatures(fl []string) ([][]string, error) {
+	lists := make([][]string, len(fl))
+	err := o.forEachFile(fl, func(i int, f string) (err error) {
+		lists[i], err = o.features(f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// forEachFile calls do for every file of fl using the number of workers set by Workers.
+// Errors are returned in the order of fl and name their file.
+func (o *options) forEachFile(fl []string, do func(i int, f string) error) error {
+	workers := o.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	errs := make([]error, len(fl))
+	files := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(fl); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range files {
+				if err := do(i, fl[i]); err != nil {
+					errs[i] = fileError(fl[i], err)
+				}
+			}
+		}()
+	}
+	for i := range fl {
+		files <- i
+	}
+	close(files)
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// fileError adds the name of the file to err unless err already has it.
+func fileError(name string, err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// lineCounts returns the number of copies of each line.
+func lineCounts(lines []string) map[string]int {
+	counts := make(map[string]int, len(lines))
+	for _, l := range lines {
+		counts[l]++
+	}
+	return counts
+}
+
+// Duplicates returns the lines found more than once with their number of copies, by file selected by globf.
+// Files without duplicated lines are absent.
+func Duplicates(contextFilesPath, globf string) (map[string]map[string]int, error) {
+	fl, err := globFiles(contextFilesPath, globf)
+	if err != nil {
+		return nil, err
+	}
+	duplicates := make(map[string]map[string]int)
+	for _, f := range fl {
+		lines, err := readLines(f)
+		if err != nil {
+			return nil, err
+		}
+		for l, n := range lineCounts(lines) {
+			if n == 1 {
+				continue
+			}
+			if duplicates[f] == nil {
+				duplicates[f] = make(map[string]int)
+			}
+			duplicates[f][l] = n
+		}
+	}
+	return duplicates, nil
+}
+
+// readLines returns the lines of filename in file order.
+func readLines(filename string) ([]string, error) {
+	bs, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(string(bs)), nil
+}
+
+// splitLines returns the lines of s.
+func splitLines(s string) []string {
+	//  TODO CRLF line ending is not handled
+	lines := strings.Split(s, "\n")
+	// A last empty line is removed as it is usually an artifact
+	if strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
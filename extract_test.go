@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"testing"
@@ -267,3 +268,360 @@ func TestCreateSupplement_fileerror(t *testing.T) {
 		t.Fatalf("got %v, want %v", err, want)
 	}
 }
+
+func TestCreateSupplement_rename(t *testing.T) {
+	w := t.TempDir()
+	if err := os.CopyFS(w, os.DirFS(path)); err != nil {
+		t.Fatal(err)
+	}
+	// A line after the common line is kept
+	last := "pkg syscall, const ZERO = 0"
+	if err := os.WriteFile(filepath.Join(w, "case_3.txt"), []byte(commonline+"\n"+last), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractCommon(w, globf, commonf); err != nil {
+		t.Fatal(err)
+	}
+	rename := func(name string) string {
+		return strings.TrimSuffix(name, ".txt") + ".supplement"
+	}
+	if err := CreateSupplements(w, globf, commonf, VerifySupplements(), SupplementName(rename)); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"case_1", "case_2", "case_3"} {
+		original, err := requiredFeatures(filepath.Join(w, f+".txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines, err := RecomposeFiles(w, commonf, f+".supplement")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(lines, original) {
+			t.Errorf("%s: got %v, want %v", f, lines, original)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(w, "case_3.supplement"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != last {
+		t.Errorf("got %q, want %q", b, last)
+	}
+	if _, err = RecomposeFiles(w, commonf, "notfound"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+// Lines sorting after the last line of the baseline are kept
+func TestSupplements_lastlines(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{
+		"case_1.txt": "b\nc\nd\ne\n",
+		"case_2.txt": "a\nb\n",
+		commonf:      "b\n",
+	})
+	got, err := Supplements(w, globf, commonf, VerifySupplements())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for f, want := range map[string][]string{
+		"case_1.txt": {"c", "d", "e"},
+		"case_2.txt": {"a"},
+	} {
+		if lines := got[filepath.Join(w, f)]; !slices.Equal(lines, want) {
+			t.Errorf("%s: got %q, want %q", f, lines, want)
+		}
+	}
+}
+
+// Verification counts duplicated lines
+func TestSupplements_verifyduplicates(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{
+		"case_1.txt": "a\nb\na\n",
+		commonf:      "a\n",
+	})
+	// A single copy of a is removed in file order like for sorted lines
+	for _, opts := range [][]Option{nil, {KeepOrder()}, {KeepOrder(), Multiset()}} {
+		got, err := Supplements(w, globf, commonf, append(opts, VerifySupplements())...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"a", "b"}
+		if len(opts) != 0 {
+			want = []string{"b", "a"}
+		}
+		if lines := got[filepath.Join(w, "case_1.txt")]; !slices.Equal(lines, want) {
+			t.Errorf("%d options: got %q, want %q", len(opts), lines, want)
+		}
+	}
+	if _, err := Supplements(w, globf, commonf, Dedupe(), VerifySupplements()); err != nil {
+		t.Error(err)
+	}
+}
+
+// No file is written when a supplement fails
+func TestCreateSupplement_nowrite(t *testing.T) {
+	w := t.TempDir()
+	if err := os.CopyFS(w, os.DirFS(path)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(w, commonf), []byte(commonline), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(w, "case_3.txt"), []byte("unknown"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateSupplements(w, globf, commonf); err == nil {
+		t.Fatal("unexpected success")
+	}
+	b, err := requiredFeatures(filepath.Join(w, "case_1.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(b), 6; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRecompose(t *testing.T) {
+	got := Recompose([]string{"b", "d"}, []string{"c", "a", "b"})
+	if want := []string{"a", "b", "b", "c", "d"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestExtractCommon_keeporder(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{
+		"case_1.txt": "# header\nz\nb\ny\na\n",
+		"case_2.txt": "a\n# header\nb\nc\nz\n",
+	})
+	if err := ExtractCommon(w, globf, commonf, KeepOrder()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(w, commonf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "# header\nz\nb\na"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err = CreateSupplements(w, globf, commonf, KeepOrder(), VerifySupplements()); err != nil {
+		t.Fatal(err)
+	}
+	for f, want := range map[string]string{
+		"case_1.txt": "y",
+		"case_2.txt": "c",
+	} {
+		b, err := os.ReadFile(filepath.Join(w, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%s: got %q, want %q", f, b, want)
+		}
+	}
+	// Common line is missing
+	if err = os.WriteFile(filepath.Join(w, "case_3.txt"), []byte("a"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err = CreateSupplements(w, globf, commonf, KeepOrder()); err == nil {
+		t.Error("unexpected success")
+	}
+	if err = os.WriteFile(filepath.Join(w, "case_3.txt"), []byte("d"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err = ExtractCommon(w, globf, commonf, KeepOrder()); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestExtractCommon_duplicates(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{
+		"case_1.txt": "a\nb\na\na\nc\n",
+		"case_2.txt": "a\nc\na\nb\nb\n",
+	})
+	for _, tc := range []struct {
+		name   string
+		opts   []Option
+		common string
+		supp1  string
+	}{
+		{"sorted", nil, "a\na\nb\nc", "a"},
+		{"sorted dedupe", []Option{Dedupe()}, "a\nb\nc", ""},
+		{"ordered", []Option{KeepOrder()}, "a\nb\na\nc", "a"},
+		{"ordered dedupe", []Option{KeepOrder(), Dedupe()}, "a\nb\nc", ""},
+		{"ordered multiset", []Option{KeepOrder(), Multiset()}, "a\nb\na\nc", "a"},
+	} {
+		if err := ExtractCommon(w, globf, commonf, tc.opts...); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		b, err := os.ReadFile(filepath.Join(w, commonf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.common {
+			t.Errorf("%s: got %q, want %q", tc.name, b, tc.common)
+		}
+		opts := append(tc.opts, SupplementName(func(name string) string { return name + ".supp" }))
+		if err = CreateSupplements(w, globf, commonf, opts...); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if b, err = os.ReadFile(filepath.Join(w, "case_1.txt.supp")); err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.supp1 {
+			t.Errorf("%s: got supplement %q, want %q", tc.name, b, tc.supp1)
+		}
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{
+		"case_1.txt": "a\nb\na\na\nc\n",
+		"case_2.txt": "a\nc\nb\n",
+	})
+	d, err := Duplicates(w, globf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d) != 1 || d[filepath.Join(w, "case_1.txt")]["a"] != 3 || len(d[filepath.Join(w, "case_1.txt")]) != 1 {
+		t.Errorf("got %v", d)
+	}
+}
+
+func TestExtractCommon_workers(t *testing.T) {
+	w := t.TempDir()
+	for i := 0; i < 20; i++ {
+		content := fmt.Sprintf("common\nline %d\nz\nshared %d\n", i, i%2)
+		if err := os.WriteFile(filepath.Join(w, fmt.Sprintf("case_%02d.txt", i)), []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, n := range []int{1, 3, 0} {
+		if err := ExtractCommon(w, globf, commonf, Workers(n)); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(filepath.Join(w, commonf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "common\nz"; string(b) != want {
+			t.Errorf("%d workers: got %q, want %q", n, b, want)
+		}
+	}
+	// Errors of every file
+	for _, f := range []string{"case_03.txt", "case_11.txt"} {
+		if err := os.WriteFile(filepath.Join(w, f), nil, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := ExtractCommon(w, globf, commonf, Workers(4))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got %v, want %v", err, fs.ErrNotExist)
+	}
+	for _, f := range []string{"case_03.txt", "case_11.txt"} {
+		if !strings.Contains(err.Error(), f) {
+			t.Errorf("%s is missing from %v", f, err)
+		}
+	}
+}
+
+func TestExtractCommon_destination(t *testing.T) {
+	w, dest := t.TempDir(), filepath.Join(t.TempDir(), "out")
+	if err := os.CopyFS(w, os.DirFS(path)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractCommon(w, globf, commonf, Destination(dest), FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filepath.Join(dest, commonf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fi.Mode().Perm(), fs.FileMode(0600); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err = os.Stat(filepath.Join(w, commonf)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+	if err = os.Rename(filepath.Join(dest, commonf), filepath.Join(w, commonf)); err != nil {
+		t.Fatal(err)
+	}
+	if err = CreateSupplements(w, globf, commonf, Destination(dest)); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"case_1.txt", "case_2.txt"} {
+		supplement, err := readLines(filepath.Join(dest, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		original, err := readLines(filepath.Join(w, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(supplement) != len(original)-1 {
+			t.Errorf("%s: got %d lines, want %d", f, len(supplement), len(original)-1)
+		}
+	}
+}
+
+// Outputs written by different funcs to the same destination replace each other
+func TestExtractCommon_rewrite(t *testing.T) {
+	dest := t.TempDir()
+	for _, content := range []string{"a\nb\n", "b\nc\n"} {
+		t.Run(content, func(t *testing.T) {
+			w := t.TempDir()
+			writeFiles(t, w, map[string]string{"case_1.txt": content, "case_2.txt": content})
+			if err := ExtractCommon(w, globf, commonf, Destination(dest)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	if b, err := os.ReadFile(filepath.Join(dest, commonf)); err != nil || string(b) != "b\nc" {
+		t.Errorf("got %q, %v, want %q", b, err, "b\nc")
+	}
+}
+
+func TestCommon(t *testing.T) {
+	intersec, err := Common(path, globf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(intersec, []string{commonline}) {
+		t.Errorf("got %q, want %q", intersec, commonline)
+	}
+	var b strings.Builder
+	if err = ExtractCommonTo(&b, path, globf); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != commonline {
+		t.Errorf("got %q, want %q", b.String(), commonline)
+	}
+	w := t.TempDir()
+	if err = os.WriteFile(filepath.Join(w, commonf), []byte(commonline), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.CopyFS(w, os.DirFS(path)); err != nil {
+		t.Fatal(err)
+	}
+	supplements, err := Supplements(w, globf, commonf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(supplements[filepath.Join(w, "case_2.txt")]); got != 3 {
+		t.Errorf("got %d lines, want 3", got)
+	}
+	// Inputs are unchanged
+	lines, err := readLines(filepath.Join(w, "case_2.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 4 {
+		t.Errorf("got %d lines, want 4", len(lines))
+	}
+}
@@ -0,0 +1,218 @@
+package testingfiles
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FactorLevel is a level of factoring. Glob selects its files and Baseline names the file of its lines.
+type FactorLevel struct {
+	Glob     string
+	Baseline string
+}
+
+// FactorNode is a node of the tree built by Factor.
+// A level holds the lines common to its files which are not in its parent. A leaf is an original file
+// and holds its lines which are not in its parent.
+type FactorNode struct {
+	Name     string   // baseline of a level, base name of the file of a leaf, empty for an added root
+	Glob     string   // glob of a level, empty for a leaf
+	Lines    []string // sorted lines proper to the node
+	Children []*FactorNode
+
+	files []string // base names of the files of the node
+	all   []string // sorted lines of the node including those of its ancestors
+}
+
+// Factor builds the tree of levels of the files of contextFilesPath.
+// A level is the child of the smallest level selecting a superset of its files, like
+// z_*.txt, z_linux_*.txt, z_linux_arm*.txt. Each file is a leaf of the smallest level selecting it.
+// When no level selects every file, the root is added and has no lines.
+// Levels selecting the same files or partially the same files are reported as an error.
+func Factor(contextFilesPath string, levels ...FactorLevel) (*FactorNode, error) {
+	if len(levels) == 0 {
+		return nil, errors.New("no level to factor")
+	}
+	var nodes []*FactorNode
+	for _, l := range levels {
+		fl, err := globFiles(contextFilesPath, l.Glob)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", l.Glob, err)
+		}
+		n := &FactorNode{Name: l.Baseline, Glob: l.Glob}
+		for _, f := range fl {
+			n.files = append(n.files, filepath.Base(f))
+		}
+		nodes = append(nodes, n)
+	}
+	// Largest levels first to place parents before children
+	slices.SortStableFunc(nodes, func(a, b *FactorNode) int {
+		return len(b.files) - len(a.files)
+	})
+	root := &FactorNode{}
+	for _, n := range nodes {
+		root.files = append(root.files, n.files...)
+	}
+	slices.Sort(root.files)
+	root.files = slices.Compact(root.files)
+	if len(nodes[0].files) == len(root.files) {
+		root = nodes[0]
+	}
+	for i, n := range nodes {
+		if n == root {
+			continue
+		}
+		parent := root
+		// Previous levels are larger, the last superset is the smallest
+		for _, p := range nodes[:i] {
+			switch {
+			case len(p.files) == len(n.files) && containsAll(p.files, n.files):
+				return nil, fmt.Errorf("%s and %s select the same files", p.Glob, n.Glob)
+			case containsAll(p.files, n.files):
+				parent = p
+			case containsAny(p.files, n.files):
+				return nil, fmt.Errorf("%s and %s select partially the same files", p.Glob, n.Glob)
+			}
+		}
+		parent.Children = append(parent.Children, n)
+	}
+	if err := root.addLeaves(contextFilesPath); err != nil {
+		return nil, err
+	}
+	root.intersect()
+	root.setLines(nil)
+	return root, nil
+}
+
+// addLeaves adds the files which are not selected by a child as leaves and reads their lines.
+func (n *FactorNode) addLeaves(contextFilesPath string) error {
+	for _, c := range n.Children {
+		if err := c.addLeaves(contextFilesPath); err != nil {
+			return err
+		}
+	}
+	for _, f := range n.files {
+		if slices.ContainsFunc(n.Children, func(c *FactorNode) bool { return slices.Contains(c.files, f) }) {
+			continue
+		}
+		lines, err := readLines(filepath.Join(contextFilesPath, f))
+		if err != nil {
+			return err
+		}
+		slices.Sort(lines)
+		n.Children = append(n.Children, &FactorNode{Name: f, files: []string{f}, all: lines})
+	}
+	return nil
+}
+
+// intersect sets the lines of a level to the intersection of the lines of its children.
+// A line found n times in every child is kept n times. An added root has no lines.
+func (n *FactorNode) intersect() {
+	if n.Glob == "" && n.Name != "" {
+		return // Leaf
+	}
+	for i, c := range n.Children {
+		c.intersect()
+		if i == 0 {
+			n.all = c.all
+			continue
+		}
+		if len(n.all) == 0 || len(c.all) == 0 {
+			n.all = nil
+			continue
+		}
+		n.all = sortedIntersection([][]string{n.all, c.all})
+	}
+	if n.Glob == "" {
+		n.all = nil
+	}
+}
+
+// setLines keeps the lines of the node which are not the ones of its parent.
+// A line found n times in the parent is removed n times.
+func (n *FactorNode) setLines(parent []string) {
+	n.Lines, _ = diffSorted(parent, n.all)
+	for _, c := range n.Children {
+		c.setLines(n.all)
+	}
+}
+
+// Recompose rebuilds the sorted lines of every original file from the lines of the nodes.
+// Files are indexed by their base name.
+func (n *FactorNode) Recompose() map[string][]string {
+	files := make(map[string][]string)
+	n.recompose(nil, files)
+	return files
+}
+
+func (n *FactorNode) recompose(ancestors []string, files map[string][]string) {
+	lines := append(slices.Clone(ancestors), n.Lines...)
+	if n.Glob == "" && len(n.Children) == 0 {
+		slices.Sort(lines)
+		files[n.Name] = lines
+		return
+	}
+	for _, c := range n.Children {
+		c.recompose(lines, files)
+	}
+}
+
+// Verify checks that every original file of contextFilesPath is rebuilt by Recompose including
+// its duplicate lines. Files which are not are reported in the error.
+func (n *FactorNode) Verify(contextFilesPath string) error {
+	var errs []error
+	for f, lines := range n.Recompose() {
+		original, err := readLines(filepath.Join(contextFilesPath, f))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		slices.Sort(original)
+		if !slices.Equal(original, lines) {
+			errs = append(errs, fmt.Errorf("%s is not recomposed", f))
+		}
+	}
+	slices.SortFunc(errs, func(a, b error) int {
+		return strings.Compare(a.Error(), b.Error())
+	})
+	return errors.Join(errs...)
+}
+
+// Write writes the lines of every level in dir using its baseline name and the lines of every leaf using
+// the name of the original file. dir can be the directory of the original files which are then replaced.
+func (n *FactorNode) Write(dir string, opts ...Option) error {
+	if n.Name != "" {
+		if err := WriteLines(filepath.Join(dir, n.Name), n.Lines, opts...); err != nil {
+			return err
+		}
+	}
+	for _, c := range n.Children {
+		if err := c.Write(dir, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedSet returns the sorted lines without duplicates.
+func sortedSet(lines []string) []string {
+	lines = slices.Clone(lines)
+	slices.Sort(lines)
+	return slices.Compact(lines)
+}
+
+func containsAll(set, sub []string) bool {
+	for _, s := range sub {
+		if !slices.Contains(set, s) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(set, sub []string) bool {
+	return slices.ContainsFunc(sub, func(s string) bool { return slices.Contains(set, s) })
+}
@@ -0,0 +1,141 @@
+package testingfiles
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// factorFiles are the platform files of the factor tests.
+var factorFiles = map[string]string{
+	"z_linux_386.txt":    "all\nlinux\nx86\n386",
+	"z_linux_arm.txt":    "all\nlinux\narm\narm32",
+	"z_linux_arm64.txt":  "all\nlinux\narm\narm64\nunix",
+	"z_darwin_amd64.txt": "all\nunix\nx86",
+}
+
+func TestFactor(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, factorFiles)
+	root, err := Factor(dir,
+		FactorLevel{Glob: "z_linux_arm*.txt", Baseline: "z_linux_arm"},
+		FactorLevel{Glob: "z_*.txt", Baseline: "z_all"},
+		FactorLevel{Glob: "z_linux_*.txt", Baseline: "z_linux"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Name != "z_all" || !slices.Equal(root.Lines, []string{"all"}) {
+		t.Errorf("got %s %v", root.Name, root.Lines)
+	}
+	var linux, arm *FactorNode
+	for _, c := range root.Children {
+		if c.Name == "z_linux" {
+			linux = c
+		}
+	}
+	if linux == nil || !slices.Equal(linux.Lines, []string{"linux"}) {
+		t.Fatalf("linux level is missing or wrong: %+v", linux)
+	}
+	for _, c := range linux.Children {
+		if c.Name == "z_linux_arm" {
+			arm = c
+		}
+	}
+	if arm == nil || !slices.Equal(arm.Lines, []string{"arm"}) || len(arm.Children) != 2 {
+		t.Fatalf("arm level is missing or wrong: %+v", arm)
+	}
+	if err = root.Verify(dir); err != nil {
+		t.Error(err)
+	}
+	files := root.Recompose()
+	if got, want := len(files), 4; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	out := t.TempDir()
+	if err = root.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	for f, want := range map[string]string{
+		"z_all":             "all",
+		"z_linux":           "linux",
+		"z_linux_arm":       "arm",
+		"z_linux_arm64.txt": "arm64\nunix",
+		"z_linux_386.txt":   "386\nx86",
+	} {
+		b, err := os.ReadFile(filepath.Join(out, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%s: got %q, want %q", f, b, want)
+		}
+	}
+	// Verify detects a change of the originals
+	if err = os.WriteFile(filepath.Join(dir, "z_linux_386.txt"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = root.Verify(dir); err == nil || !strings.Contains(err.Error(), "z_linux_386.txt") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestFactor_addedroot(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, factorFiles)
+	root, err := Factor(dir,
+		FactorLevel{Glob: "z_linux_arm*.txt", Baseline: "z_linux_arm"},
+		FactorLevel{Glob: "z_*_386.txt", Baseline: "z_386"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Name != "" || len(root.Lines) != 0 || len(root.Children) != 2 {
+		t.Errorf("unexpected root %+v", root)
+	}
+	if err = root.Verify(dir); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFactor_invalid(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, factorFiles)
+	for _, levels := range [][]FactorLevel{
+		nil,
+		{{Glob: "notfound"}},
+		{{Glob: "z_linux_*.txt"}, {Glob: "z_linux_*"}},
+		{{Glob: "z_*_386.txt"}, {Glob: "z_linux_38*.txt"}, {Glob: "z_*_a*.txt"}, {Glob: "z_linux_a*.txt"}},
+	} {
+		if _, err := Factor(dir, levels...); err == nil {
+			t.Errorf("%v: no error", levels)
+		}
+	}
+}
+
+// Duplicate lines are kept in the nodes which rebuild the originals
+func TestFactor_duplicates(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"z_a.txt": "c\nx\nx\n",
+		"z_b.txt": "c\nx\n",
+	})
+	root, err := Factor(dir, FactorLevel{Glob: "z_*.txt", Baseline: "z_all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = root.Verify(dir); err != nil {
+		t.Error(err)
+	}
+	if got, want := root.Recompose()["z_a.txt"], []string{"c", "x", "x"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "z_a.txt"), []byte("c\nx\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = root.Verify(dir); err == nil || !strings.Contains(err.Error(), "z_a.txt") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package testingfiles
+
+import "strings"
+
+// SkipBlank ignores lines which are empty or hold only white space.
+func SkipBlank() Option {
+	return func(o *options) {
+		o.skipBlank = true
+	}
+}
+
+// SkipComments ignores lines starting with one of the prefixes, like # or //, after leading white space.
+func SkipComments(prefixes ...string) Option {
+	return func(o *options) {
+		o.comments = append(o.comments, prefixes...)
+	}
+}
+
+// TrimSpace removes leading and trailing white space of lines.
+func TrimSpace() Option {
+	return func(o *options) {
+		o.trimSpace = true
+	}
+}
+
+// FoldCase compares lines ignoring case. Lines are filtered to their lower case which is the case
+// of the output lines.
+func FoldCase() Option {
+	return func(o *options) {
+		o.foldCase = true
+	}
+}
+
+// KeepHeader writes the leading comments and blank lines of the first file at the top of the common file,
+// and those of each file at the top of its supplement. Comments are set by SkipComments and KeepHeader
+// does nothing without it.
+func KeepHeader() Option {
+	return func(o *options) {
+		o.keepHeader = true
+	}
+}
+
+// filter returns the lines which are kept by the filters. Lines are trimmed with TrimSpace and
+// in lower case with FoldCase.
+func (o *options) filter(lines []string) []string {
+	if !o.skipBlank && !o.trimSpace && !o.foldCase && len(o.comments) == 0 {
+		return lines
+	}
+	kept := lines[:0]
+	for _, l := range lines {
+		if l, ok := o.filterLine(l); ok {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
+
+// filterLine returns the line to use and whether it is kept.
+func (o *options) filterLine(l string) (string, bool) {
+	if o.trimSpace {
+		l = strings.TrimSpace(l)
+	}
+	if o.skipBlank && strings.TrimSpace(l) == "" || o.isComment(l) {
+		return "", false
+	}
+	if o.foldCase {
+		l = strings.ToLower(l)
+	}
+	return l, true
+}
+
+func (o *options) isComment(l string) bool {
+	l = strings.TrimSpace(l)
+	for _, p := range o.comments {
+		if strings.HasPrefix(l, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// withHeader returns lines preceded by the header of filename when KeepHeader is used.
+func (o *options) withHeader(filename string, lines []string) ([]string, error) {
+	if !o.keepHeader || len(o.comments) == 0 {
+		return lines, nil
+	}
+	all, err := o.readLines(filename)
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for n < len(all) && (strings.TrimSpace(all[n]) == "" || o.isComment(all[n])) {
+		n++
+	}
+	// Blank lines between the header and the first line are not kept
+	for n > 0 && strings.TrimSpace(all[n-1]) == "" {
+		n--
+	}
+	if n == 0 {
+		return lines, nil
+	}
+	return append(all[:n:n], lines...), nil
+}
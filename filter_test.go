@@ -0,0 +1,78 @@
+package testingfiles
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExtractCommon_filter(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{
+		"case_1.txt": "# generated by a tool\n# version 1\n\nAlpha  \n\n// note\nbeta\ngamma\n",
+		"case_2.txt": "# version 2\nalpha\n   \nBeta\n  # indented\ndelta\n",
+	})
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"none", nil, nil},
+		{"blank", []Option{SkipBlank()}, nil},
+		{"trim fold", []Option{TrimSpace(), FoldCase()}, []string{"", "alpha", "beta"}},
+		{"all", []Option{SkipBlank(), SkipComments("#", "//"), TrimSpace(), FoldCase()},
+			[]string{"alpha", "beta"}},
+		{"header", []Option{SkipBlank(), SkipComments("#", "//"), TrimSpace(), FoldCase(), KeepHeader()},
+			[]string{"# generated by a tool", "# version 1", "alpha", "beta"}},
+	} {
+		got, err := Common(w, globf, tc.opts...)
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("%s: got %q, want an error", tc.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+	if err := WriteLines(filepath.Join(w, commonf), []string{"alpha", "beta"}); err != nil {
+		t.Fatal(err)
+	}
+	supplements, err := Supplements(w, globf, commonf, SkipBlank(), SkipComments("#", "//"), TrimSpace(),
+		FoldCase(), KeepHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := supplements[filepath.Join(w, "case_2.txt")], []string{"# version 2", "delta"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// Folded lines keep the semantics of duplicated lines
+func TestCommon_foldcase(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{
+		"case_1.txt": "B\nA\na\nb\n",
+		"case_2.txt": "a\nA\nb\n",
+	})
+	for _, tc := range []struct {
+		opts []Option
+		want []string
+	}{
+		{[]Option{FoldCase()}, []string{"a", "a", "b"}},
+		{[]Option{FoldCase(), Dedupe()}, []string{"a", "b"}},
+		{[]Option{FoldCase(), KeepOrder(), Multiset()}, []string{"b", "a", "a"}},
+	} {
+		got, err := Common(w, globf, tc.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package testingfiles
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// failOrphans makes CheckOrphans return an error when golden files were not read.
+var failOrphans = flag.Bool("testingfiles.failorphans", false,
+	"fail when golden files of the output directory are not read by any test")
+
+// goldens records the absolute path of every golden (want) file used by a compare function.
+var goldens = struct {
+	sync.Mutex
+	m map[string]bool
+}{m: make(map[string]bool)}
+
+// useGolden registers want as read during the run.
+// The path is made absolute as the working directory might change between tests.
+func useGolden(want string) {
+	p, err := filepath.Abs(want)
+	if err != nil {
+		p = want
+	}
+	goldens.Lock()
+	goldens.m[p] = true
+	goldens.Unlock()
+}
+
+// UseGoldens registers golden files used elsewhere, like the list of Goldens saved by another run.
+func UseGoldens(names ...string) {
+	for _, n := range names {
+		useGolden(n)
+	}
+}
+
+// Goldens returns the sorted absolute paths of the golden files used by compare functions.
+func Goldens() []string {
+	goldens.Lock()
+	defer goldens.Unlock()
+	l := make([]string, 0, len(goldens.m))
+	for p := range goldens.m {
+		l = append(l, p)
+	}
+	slices.Sort(l)
+	return l
+}
+
+// Orphans returns the files of dir and of its subdirectories matching pattern which were not used by any compare
+// function. pattern is matched against the path relative to dir, or against the base name when it holds no
+// separator. Directories and got files are ignored.
+func Orphans(dir, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	goldens.Lock()
+	defer goldens.Unlock()
+	var orphans []string
+	err := filepath.WalkDir(dir, func(f string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if f == dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), "got_") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, f)
+		if err != nil {
+			return err
+		}
+		if !strings.ContainsRune(pattern, filepath.Separator) {
+			rel = d.Name()
+		}
+		if ok, _ := filepath.Match(pattern, rel); !ok {
+			return nil
+		}
+		p, err := filepath.Abs(f)
+		if err != nil {
+			return err
+		}
+		if !goldens.m[p] {
+			orphans = append(orphans, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orphans, nil
+}
+
+// RemoveOrphans deletes the orphans of dir matching pattern and returns their names.
+func RemoveOrphans(dir, pattern string) ([]string, error) {
+	orphans, err := Orphans(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range orphans {
+		if err = os.Remove(f); err != nil {
+			return orphans[:i], err
+		}
+		log.Printf("orphan %s removed", f)
+	}
+	return orphans, nil
+}
+
+// CheckOrphans logs the orphans of dir matching pattern. It is meant to be called from TestMain after m.Run.
+// An error is returned only if orphans exist and -testingfiles.failorphans is set.
+func CheckOrphans(dir, pattern string) error {
+	orphans, err := Orphans(dir, pattern)
+	if err != nil {
+		return err
+	}
+	for _, f := range orphans {
+		log.Printf("%s is not used by any test", f)
+	}
+	if len(orphans) != 0 && *failOrphans {
+		return fmt.Errorf("%d golden file(s) are not used by any test", len(orphans))
+	}
+	return nil
+}
+
+// names records the golden names returned by GoldenName with the test using them.
+var names = struct {
+	sync.Mutex
+	ext string
+	m   map[string]string // lower case name to test name
+}{m: make(map[string]string)}
+
+// SetGoldenExt sets the extension, like ".golden", appended to names returned by GoldenName.
+// Extension is empty by default and the name is the one of the test.
+func SetGoldenExt(ext string) {
+	names.Lock()
+	names.ext = ext
+	names.Unlock()
+}
+
+// GoldenName returns the name of the golden file of t which is relative to the working directory.
+// Each level of subtests is a subdirectory which is created when missing. Characters which are invalid in
+// a file name on common file systems are escaped.
+// An error is returned when another test maps to the same name, or when the name of a test is also the
+// directory of the subtests of another test, like the golden file of a test having subtests without extension.
+// As some file systems ignore case, names differing only by case are also reported.
+func GoldenName(t testing.TB) (string, error) {
+	segments := strings.Split(t.Name(), "/")
+	for i, s := range segments {
+		segments[i] = escapeSegment(s)
+	}
+	names.Lock()
+	defer names.Unlock()
+	name := filepath.Join(segments...) + names.ext
+	key := strings.ToLower(name)
+	if other, ok := names.m[key]; ok && other != t.Name() {
+		return "", fmt.Errorf("golden file %s of %s is already used by %s", name, t.Name(), other)
+	}
+	sep := string(filepath.Separator)
+	for k, other := range names.m {
+		if strings.HasPrefix(key, k+sep) || strings.HasPrefix(k, key+sep) {
+			return "", fmt.Errorf("golden file %s of %s clashes with the golden file of %s", name, t.Name(), other)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return "", err
+	}
+	names.m[key] = t.Name()
+	return name, nil
+}
+
+// reservedNames are unavailable as file names on Windows, whatever the extension.
+var reservedNames = []string{"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9"}
+
+// escapeSegment escapes a path segment using %XX for characters invalid in a file name.
+// Escaping % keeps distinct segments distinct. Trailing dots and spaces, . and .. and reserved names are
+// escaped too.
+func escapeSegment(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < ' ' || c == 0x7f || strings.IndexByte(`%\:*?"<>|`, c) != -1 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	e := b.String()
+	if e == "" {
+		return "%"
+	}
+	base, _, _ := strings.Cut(e, ".")
+	if slices.Contains(reservedNames, strings.ToUpper(base)) {
+		e = fmt.Sprintf("%%%02X", e[0]) + e[1:]
+	}
+	if last := e[len(e)-1]; last == '.' || last == ' ' {
+		e = e[:len(e)-1] + fmt.Sprintf("%%%02X", last)
+	}
+	return e
+}
@@ -0,0 +1,163 @@
+package testingfiles
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestOrphans(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"used":                               "a",
+		"orphan":                             "a",
+		"got_used":                           "a",
+		filepath.Join("subdir", "suborphan"): "a",
+	})
+	b := bytes.NewBufferString("a")
+	if err := BufferCompare(b, filepath.Join(dir, "used")); err != nil {
+		t.Fatal(err)
+	}
+	p, err := filepath.Abs(filepath.Join(dir, "used"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(Goldens(), p) {
+		t.Errorf("%s is not registered", p)
+	}
+	orphans, err := Orphans(dir, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "orphan"), filepath.Join(dir, "subdir", "suborphan")}
+	if !slices.Equal(orphans, want) {
+		t.Errorf("got %v, want %v", orphans, want)
+	}
+	if err = CheckOrphans(dir, "*"); err != nil {
+		t.Errorf("failure is not requested: %v", err)
+	}
+	*failOrphans = true
+	defer func() {
+		*failOrphans = false
+	}()
+	if err = CheckOrphans(dir, "*"); err == nil {
+		t.Error("orphans did not fail")
+	}
+	removed, err := RemoveOrphans(dir, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(removed, want) {
+		t.Errorf("got %v, want %v", removed, want)
+	}
+	if err = CheckOrphans(dir, "*"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOrphans_relative(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.golden":                         "a",
+		filepath.Join("TestX", "a.golden"): "a",
+		filepath.Join("TestX", "b.txt"):    "a",
+	})
+	orphans, err := Orphans(dir, filepath.Join("TestX", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "TestX", "a.golden"), filepath.Join(dir, "TestX", "b.txt")}
+	if !slices.Equal(orphans, want) {
+		t.Errorf("got %v, want %v", orphans, want)
+	}
+	if orphans, err = Orphans(filepath.Join(dir, "missing"), "*"); err != nil || orphans != nil {
+		t.Errorf("got %v, %v, want none", orphans, err)
+	}
+}
+
+func TestOrphans_badpattern(t *testing.T) {
+	if _, err := Orphans(t.TempDir(), "["); err != filepath.ErrBadPattern {
+		t.Errorf("got %v, want %v", err, filepath.ErrBadPattern)
+	}
+}
+
+func TestGoldenName(t *testing.T) {
+	t.Cleanup(func() {
+		_ = os.RemoveAll("TestGoldenName")
+	})
+	SetGoldenExt(".golden")
+	defer SetGoldenExt("")
+	name, err := GoldenName(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := t.Name() + ".golden"; name != want {
+		t.Errorf("got %s, want %s", name, want)
+	}
+	t.Run("sub:case", func(t *testing.T) {
+		name, err := GoldenName(t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join("TestGoldenName", "sub%3Acase.golden"); name != want {
+			t.Errorf("got %s, want %s", name, want)
+		}
+		if fi, err := os.Stat(filepath.Dir(name)); err != nil || !fi.IsDir() {
+			t.Errorf("directory of %s is not created: %v", name, err)
+		}
+		// same test, same name
+		if _, err = GoldenName(t); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("Case", func(t *testing.T) {
+		if _, err := GoldenName(t); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("case", func(t *testing.T) {
+		if _, err := GoldenName(t); err == nil {
+			t.Error("collision is not reported")
+		}
+	})
+}
+
+// Without extension, the golden file of a test is the directory of its subtests.
+func TestGoldenName_parent(t *testing.T) {
+	t.Cleanup(func() {
+		_ = os.RemoveAll("TestGoldenName_parent")
+	})
+	name, err := GoldenName(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != t.Name() {
+		t.Errorf("got %s, want %s", name, t.Name())
+	}
+	t.Run("sub", func(t *testing.T) {
+		if name, err := GoldenName(t); err == nil {
+			t.Errorf("clash of %s with the file of the parent test is not reported", name)
+		}
+	})
+}
+
+func TestEscapeSegment(t *testing.T) {
+	for s, want := range map[string]string{
+		"":        "%",
+		".":       "%2E",
+		"..":      ".%2E",
+		"a?b":     "a%3Fb",
+		"50%":     "50%25",
+		"con":     "%63on",
+		"NUL.txt": "%4EUL.txt",
+		"end ":    "end%20",
+		"tab\t":   "tab%09",
+		"console": "console",
+	} {
+		if got := escapeSegment(s); got != want {
+			t.Errorf("%q: got %s, want %s", s, got, want)
+		}
+	}
+}
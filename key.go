@@ -0,0 +1,144 @@
+package testingfiles
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Key compares lines using the key returned by key instead of the whole line, like the name of a symbol
+// without its value. The lines of the first file are kept in the intersection.
+func Key(key func(line string) string) Option {
+	return func(o *options) {
+		o.key = key
+	}
+}
+
+// KeyRegexp compares lines using the first submatch of re, or the whole match when re has no group.
+// A line which does not match is its own key.
+func KeyRegexp(re *regexp.Regexp) Option {
+	return Key(func(line string) string {
+		m := re.FindStringSubmatch(line)
+		switch {
+		case m == nil:
+			return line
+		case len(m) > 1:
+			return m[1]
+		}
+		return m[0]
+	})
+}
+
+// keyed reports whether lines are compared using a key.
+func (o *options) keyed() bool {
+	return o.key != nil
+}
+
+// lineKey returns the key of line which is the line itself unless Key is used.
+func (o *options) lineKey(line string) string {
+	if o.key != nil {
+		return o.key(line)
+	}
+	return line
+}
+
+// keySet returns the sorted keys of lines without duplicates.
+func (o *options) keySet(lines []string) []string {
+	keys := make([]string, len(lines))
+	for i, l := range lines {
+		keys[i] = o.lineKey(l)
+	}
+	return sortedSet(keys)
+}
+
+// keyedIntersection returns the lines of the first list whose key is found in all lists.
+func keyedIntersection(lists [][]string, o *options) []string {
+	intersec := slices.Clone(lists[0])
+	for _, lines := range lists[1:] {
+		keys := o.keySet(lines)
+		intersec = slices.DeleteFunc(intersec, func(l string) bool {
+			_, found := slices.BinarySearch(keys, o.lineKey(l))
+			return !found
+		})
+	}
+	return intersec
+}
+
+// keyedSupplement returns the lines of f whose key is not in the baseline, and the lines of f.
+// It fails with a *MissingLinesError if keys of the baseline are missing from f.
+func keyedSupplement(f string, baseline []string, o *options) (supplement, original []string, err error) {
+	original, err = o.features(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, base := o.keySet(original), o.keySet(baseline)
+	var missing []string
+	for _, k := range base {
+		if _, found := slices.BinarySearch(keys, k); !found {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) != 0 {
+		return nil, nil, &MissingLinesError{File: f, Lines: missing}
+	}
+	for _, l := range original {
+		if _, found := slices.BinarySearch(base, o.lineKey(l)); !found {
+			supplement = append(supplement, l)
+		}
+	}
+	return supplement, original, nil
+}
+
+// KeyConflict is a key whose lines differ between files, like a constant with a value by platform.
+type KeyConflict struct {
+	Key   string              `json:"key"`
+	Lines map[string][]string `json:"lines"` // sorted lines of the key by file holding it
+}
+
+// KeyConflicts returns the keys of the files selected by globf whose lines are not the same in all files
+// holding them. Key or KeyRegexp sets the key. Lines are filtered like for Common. Conflicts are sorted by key.
+func KeyConflicts(contextFilesPath, globf string, opts ...Option) ([]KeyConflict, error) {
+	o := newOptions(opts)
+	if o.key == nil {
+		return nil, errors.New("no key to compare lines")
+	}
+	fl, err := globFiles(contextFilesPath, globf)
+	if err != nil {
+		return nil, err
+	}
+	lists := make([][]string, len(fl))
+	err = o.forEachFile(fl, func(i int, f string) (err error) {
+		lines, err := o.readLines(f)
+		lists[i] = o.filter(lines)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]map[string][]string)
+	for i, lines := range lists {
+		for _, l := range lines {
+			k := o.lineKey(l)
+			if byKey[k] == nil {
+				byKey[k] = make(map[string][]string)
+			}
+			byKey[k][fl[i]] = append(byKey[k][fl[i]], l)
+		}
+	}
+	var conflicts []KeyConflict
+	for k, files := range byKey {
+		var all []string
+		for f, lines := range files {
+			files[f] = sortedSet(lines)
+			all = append(all, files[f]...)
+		}
+		if len(sortedSet(all)) > 1 {
+			conflicts = append(conflicts, KeyConflict{Key: k, Lines: files})
+		}
+	}
+	slices.SortFunc(conflicts, func(a, b KeyConflict) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return conflicts, nil
+}
@@ -0,0 +1,99 @@
+package testingfiles
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var symbol = regexp.MustCompile(`^(pkg [^,]*, const [^ ]*)`)
+
+func TestExtractCommon_key(t *testing.T) {
+	w := t.TempDir()
+	if err := os.CopyFS(w, os.DirFS(path)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractCommon(w, globf, commonf, KeyRegexp(symbol)); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(w, commonf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pkg syscall, const BIOCGDLTLIST = 3221766779\n" +
+		"pkg syscall, const BIOCGRTIMEOUT = 1074545262\n" +
+		"pkg syscall, const BIOCSETF = 2148024935\n" + commonline
+	if string(b) != want {
+		t.Fatalf("got %q, want %q", b, want)
+	}
+	if err = CreateSupplements(w, globf, commonf, KeyRegexp(symbol), VerifySupplements()); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := readLines(filepath.Join(w, "case_2.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(lines, "pkg syscall, const BIOCSETF = 2148549223") {
+		t.Errorf("supplement %q holds a common key", lines)
+	}
+}
+
+func TestKeyConflicts(t *testing.T) {
+	if _, err := KeyConflicts(path, globf); err == nil {
+		t.Error("unexpected success without key")
+	}
+	conflicts, err := KeyConflicts(path, globf, KeyRegexp(symbol))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 3 {
+		t.Fatalf("got %v, want 3 conflicts", conflicts)
+	}
+	c := conflicts[2]
+	if c.Key != "pkg syscall, const BIOCSETF" {
+		t.Errorf("got key %q", c.Key)
+	}
+	for f, want := range map[string]string{
+		"case_1.txt": "pkg syscall, const BIOCSETF = 2148024935",
+		"case_2.txt": "pkg syscall, const BIOCSETF = 2148549223",
+	} {
+		if got := c.Lines[filepath.Join(path, f)]; !slices.Equal(got, []string{want}) {
+			t.Errorf("%s: got %q, want %q", f, got, want)
+		}
+	}
+}
+
+// Filtered lines are not keys
+func TestKeyConflicts_filter(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{
+		"case_1.txt": "# x=1\n\nk=1\n",
+		"case_2.txt": "# x=2\n\nk=1\n",
+	})
+	byName := Key(func(l string) string {
+		k, _, _ := strings.Cut(l, "=")
+		return k
+	})
+	conflicts, err := KeyConflicts(w, globf, byName, SkipBlank(), SkipComments("#"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("got %v, want none", conflicts)
+	}
+}
+
+func TestKeyRegexp(t *testing.T) {
+	o := newOptions([]Option{KeyRegexp(regexp.MustCompile(`const \w+`))})
+	for line, want := range map[string]string{
+		"pkg syscall, const AF_ALG = 38": "const AF_ALG",
+		"no match":                       "no match",
+	} {
+		if got := o.lineKey(line); got != want {
+			t.Errorf("%q: got %q, want %q", line, got, want)
+		}
+	}
+}
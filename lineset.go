@@ -0,0 +1,140 @@
+package testingfiles
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SetOp is an operation over the sets of lines of files.
+type SetOp int
+
+const (
+	Union               SetOp = iota // lines of any file
+	Intersection                     // lines of every file
+	Difference                       // lines of the first file which are absent from the others
+	SymmetricDifference              // lines of exactly one file
+)
+
+func (op SetOp) String() string {
+	switch op {
+	case Union:
+		return "union"
+	case Intersection:
+		return "intersection"
+	case Difference:
+		return "difference"
+	case SymmetricDifference:
+		return "symmetric difference"
+	}
+	return fmt.Sprintf("SetOp(%d)", int(op))
+}
+
+// SetLines applies op to the lines of the files of contextFilesPath selected by globf.
+// Files are used in the order returned by filepath.Glob which matters only for Difference.
+// Duplicated lines are counted once and an empty file is an empty set.
+// Lines are returned sorted. fs.ErrNotExist is returned when no file is selected.
+func SetLines(contextFilesPath, globf string, op SetOp) ([]string, error) {
+	fl, err := globFiles(contextFilesPath, globf)
+	if err != nil {
+		return nil, err
+	}
+	counts, first, err := countLines(fl)
+	if err != nil {
+		return nil, err
+	}
+	var keep func(l string, n int) bool
+	switch op {
+	case Union:
+		keep = func(string, int) bool { return true }
+	case Intersection:
+		keep = func(_ string, n int) bool { return n == len(fl) }
+	case Difference:
+		keep = func(l string, n int) bool { return n == 1 && first[l] }
+	case SymmetricDifference:
+		keep = func(_ string, n int) bool { return n == 1 }
+	default:
+		return nil, fmt.Errorf("unknown set operation %v", op)
+	}
+	return selectLines(counts, keep), nil
+}
+
+// AtLeast returns the sorted lines present in at least k of the files of contextFilesPath selected by globf.
+func AtLeast(contextFilesPath, globf string, k int) ([]string, error) {
+	fl, err := globFiles(contextFilesPath, globf)
+	if err != nil {
+		return nil, err
+	}
+	if k < 1 || k > len(fl) {
+		return nil, fmt.Errorf("%d is out of range [1, %d]", k, len(fl))
+	}
+	counts, _, err := countLines(fl)
+	if err != nil {
+		return nil, err
+	}
+	return selectLines(counts, func(_ string, n int) bool { return n >= k }), nil
+}
+
+// WriteLines writes lines separated by a new line to the file name like WriteFile.
+func WriteLines(name string, lines []string, opts ...Option) error {
+	return WriteFile(name, []byte(strings.Join(lines, "\n")), opts...)
+}
+
+// ReadLines returns the lines of the file name without the empty line following the last new line.
+// Lines are decoded and filtered like extraction functions do using the options.
+func ReadLines(name string, opts ...Option) ([]string, error) {
+	o := newOptions(opts)
+	lines, err := o.readLines(name)
+	if err != nil {
+		return nil, err
+	}
+	return o.filter(lines), nil
+}
+
+// globFiles returns the files of contextFilesPath selected by globf or fs.ErrNotExist when none is.
+func globFiles(contextFilesPath, globf string) ([]string, error) {
+	fl, err := filepath.Glob(filepath.Join(contextFilesPath, globf))
+	if err != nil {
+		return nil, err
+	}
+	if len(fl) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	return fl, nil
+}
+
+// countLines returns the number of files holding each line and the lines of the first file.
+func countLines(fl []string) (counts map[string]int, first map[string]bool, err error) {
+	counts = make(map[string]int)
+	for i, f := range fl {
+		lines, err := readLines(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		seen := make(map[string]bool, len(lines))
+		for _, l := range lines {
+			if !seen[l] {
+				seen[l] = true
+				counts[l]++
+			}
+		}
+		if i == 0 {
+			first = seen
+		}
+	}
+	return counts, first, nil
+}
+
+// selectLines returns the sorted lines of counts for which keep is true.
+func selectLines(counts map[string]int, keep func(l string, n int) bool) []string {
+	lines := []string{}
+	for l, n := range counts {
+		if keep(l, n) {
+			lines = append(lines, l)
+		}
+	}
+	slices.Sort(lines)
+	return lines
+}
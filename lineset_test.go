@@ -0,0 +1,80 @@
+package testingfiles
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSetLines(t *testing.T) {
+	for op, want := range map[SetOp]int{
+		Union:               9,
+		Intersection:        1,
+		Difference:          5,
+		SymmetricDifference: 8,
+	} {
+		lines, err := SetLines(path, globf, op)
+		if err != nil {
+			t.Fatalf("%v: %v", op, err)
+		}
+		if got := len(lines); got != want {
+			t.Errorf("%v: got %v, want %v", op, got, want)
+		}
+		if !slices.IsSorted(lines) {
+			t.Errorf("%v: lines are not sorted", op)
+		}
+	}
+	lines, err := SetLines(path, globf, Intersection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{commonline}; !slices.Equal(lines, want) {
+		t.Errorf("got %v, want %v", lines, want)
+	}
+	if _, err = SetLines(path, globf, SetOp(-1)); err == nil {
+		t.Error("unknown operation succeeded")
+	}
+	if _, err = SetLines(path, "notfound", Union); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"case_1.txt": "a\nb\nc\n",
+		"case_2.txt": "b\nc\nc",
+		"case_3.txt": "c\nd",
+		"case_4.txt": "",
+	})
+	for k, want := range map[int][]string{
+		1: {"a", "b", "c", "d"},
+		2: {"b", "c"},
+		3: {"c"},
+		4: {},
+	} {
+		lines, err := AtLeast(dir, globf, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(lines, want) {
+			t.Errorf("%d: got %v, want %v", k, lines, want)
+		}
+	}
+	if _, err := AtLeast(dir, globf, 5); err == nil {
+		t.Error("k larger than the number of files succeeded")
+	}
+}
+
+func TestWriteLines(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "lines")
+	if err := WriteLines(name, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(name); err != nil || string(b) != "a\nb" {
+		t.Errorf("got %q, %v", b, err)
+	}
+}
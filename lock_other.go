@@ -0,0 +1,8 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package testingfiles
+
+// lockDir is a no-op as advisory locks are unavailable. Writers are only serialized in the process.
+func lockDir(string) (unlock func(), err error) {
+	return func() {}, nil
+}
@@ -0,0 +1,36 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package testingfiles
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockDir(t *testing.T) {
+	dir := t.TempDir()
+	unlock, err := lockDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked := make(chan error)
+	go func() {
+		unlock, err := lockDir(dir)
+		if err == nil {
+			unlock()
+		}
+		locked <- err
+	}()
+	select {
+	case <-locked:
+		t.Fatal("directory is locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	if err = <-locked; err != nil {
+		t.Error(err)
+	}
+	if _, err = lockDir("doesnotexist"); err == nil {
+		t.Error("locking a missing directory succeeded")
+	}
+}
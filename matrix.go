@@ -0,0 +1,166 @@
+package testingfiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Matrix lists the lines of platform specific files with the platforms declaring them.
+type Matrix struct {
+	Platforms []string      `json:"platforms"` // sorted platforms
+	Lines     []MatrixLine  `json:"lines"`     // sorted lines
+	Groups    []MatrixGroup `json:"groups"`    // lines grouped by identical sets of platforms
+}
+
+// MatrixLine is a line and the sorted platforms declaring it.
+type MatrixLine struct {
+	Line      string   `json:"line"`
+	Platforms []string `json:"platforms"`
+}
+
+// MatrixGroup holds the lines declared by the same platforms.
+// Label describes the platforms like "all", "all except mips*" or "386, amd64".
+type MatrixGroup struct {
+	Label     string   `json:"label"`
+	Platforms []string `json:"platforms"`
+	Lines     []string `json:"lines"`
+}
+
+// NewMatrix builds the matrix of the files of contextFilesPath selected by globf.
+// The platform of a file is the part of its name matched by the wildcard when globf has a single *,
+// like linux_arm64 for z_syscall_linux_arm64.txt and z_syscall_*.txt. Otherwise, it is the name of the file
+// without extension.
+func NewMatrix(contextFilesPath, globf string) (*Matrix, error) {
+	fl, err := globFiles(contextFilesPath, globf)
+	if err != nil {
+		return nil, err
+	}
+	m := &Matrix{}
+	declared := make(map[string][]string)
+	for _, f := range fl {
+		p := platformName(globf, filepath.Base(f))
+		m.Platforms = append(m.Platforms, p)
+		lines, err := readLines(f)
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(lines)
+		for _, l := range slices.Compact(lines) {
+			declared[l] = append(declared[l], p)
+		}
+	}
+	slices.Sort(m.Platforms)
+	groups := make(map[string]*MatrixGroup)
+	for l, platforms := range declared {
+		slices.Sort(platforms)
+		m.Lines = append(m.Lines, MatrixLine{Line: l, Platforms: platforms})
+		k := strings.Join(platforms, "\n")
+		g, ok := groups[k]
+		if !ok {
+			g = &MatrixGroup{Label: platformsLabel(platforms, m.Platforms), Platforms: platforms}
+			groups[k] = g
+		}
+		g.Lines = append(g.Lines, l)
+	}
+	slices.SortFunc(m.Lines, func(a, b MatrixLine) int {
+		return strings.Compare(a.Line, b.Line)
+	})
+	for _, g := range groups {
+		slices.Sort(g.Lines)
+		m.Groups = append(m.Groups, *g)
+	}
+	// Most shared lines first
+	slices.SortFunc(m.Groups, func(a, b MatrixGroup) int {
+		if len(a.Platforms) != len(b.Platforms) {
+			return len(b.Platforms) - len(a.Platforms)
+		}
+		return strings.Compare(strings.Join(a.Platforms, ","), strings.Join(b.Platforms, ","))
+	})
+	return m, nil
+}
+
+// WriteText writes the matrix as a table of lines preceded by the label of their platforms, group by group.
+func (m *Matrix) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PLATFORMS\tLINE")
+	for _, g := range m.Groups {
+		for _, l := range g.Lines {
+			fmt.Fprintf(tw, "%s\t%s\n", g.Label, l)
+		}
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the matrix as indented JSON.
+func (m *Matrix) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(m)
+}
+
+// platformName returns the part of name matched by the single * of pattern.
+// Otherwise, name without extension is returned.
+func platformName(pattern, name string) string {
+	pattern = filepath.Base(pattern)
+	prefix, suffix, found := strings.Cut(pattern, "*")
+	if found && !strings.ContainsAny(prefix+suffix, `*?[\`) &&
+		len(name) >= len(prefix)+len(suffix) && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, suffix) {
+		if p := name[len(prefix) : len(name)-len(suffix)]; p != "" {
+			return p
+		}
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// platformsLabel describes the sorted platforms among all using "all" and "except" when it is shorter.
+// Platforms sharing a prefix of letters are listed as prefix* when all of them are present.
+func platformsLabel(platforms, all []string) string {
+	if len(platforms) == len(all) {
+		return "all"
+	}
+	if len(platforms) > len(all)/2 {
+		var excluded []string
+		for _, p := range all {
+			if _, found := slices.BinarySearch(platforms, p); !found {
+				excluded = append(excluded, p)
+			}
+		}
+		return "all except " + strings.Join(compressPlatforms(excluded, all), ", ")
+	}
+	return strings.Join(compressPlatforms(platforms, all), ", ")
+}
+
+// compressPlatforms replaces platforms sharing a prefix of letters by prefix* when no platform of all
+// with this prefix is missing.
+func compressPlatforms(platforms, all []string) []string {
+	var l []string
+	done := make(map[string]bool)
+	for _, p := range platforms {
+		prefix := p[:strings.IndexFunc(p+"0", func(r rune) bool { return r < 'a' || r > 'z' })]
+		if prefix == "" {
+			l = append(l, p)
+			continue
+		}
+		n, complete := 0, true
+		for _, q := range all {
+			if strings.HasPrefix(q, prefix) {
+				n++
+				complete = complete && slices.Contains(platforms, q)
+			}
+		}
+		if n < 2 || !complete {
+			l = append(l, p)
+			continue
+		}
+		if !done[prefix] {
+			done[prefix] = true
+			l = append(l, prefix+"*")
+		}
+	}
+	return l
+}
@@ -0,0 +1,69 @@
+package testingfiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewMatrix(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"z_linux_386.txt":      "all\nx86\nnomips\n",
+		"z_linux_amd64.txt":    "all\nx86\nnomips\n",
+		"z_linux_arm.txt":      "all\nnomips\n",
+		"z_linux_mips.txt":     "all\nmips",
+		"z_linux_mips64le.txt": "all\nmips\nmips",
+	})
+	m, err := NewMatrix(dir, "z_linux_*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"386", "amd64", "arm", "mips", "mips64le"}; !slices.Equal(m.Platforms, want) {
+		t.Errorf("got %v, want %v", m.Platforms, want)
+	}
+	if got, want := len(m.Lines), 4; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	var labels []string
+	for _, g := range m.Groups {
+		labels = append(labels, g.Label)
+	}
+	if want := []string{"all", "all except mips*", "386, amd64", "mips*"}; !slices.Equal(labels, want) {
+		t.Errorf("got %q, want %q", labels, want)
+	}
+	b := new(bytes.Buffer)
+	if err = m.WriteText(b); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Count(b.String(), "\n"), len(m.Lines)+1; got != want {
+		t.Errorf("got %v, want %v lines in\n%s", got, want, b)
+	}
+	b.Reset()
+	if err = m.WriteJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Matrix
+	if err = json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Groups) != len(m.Groups) {
+		t.Errorf("got %v, want %v", decoded.Groups, m.Groups)
+	}
+}
+
+func TestPlatformName(t *testing.T) {
+	for _, c := range []struct{ pattern, name, want string }{
+		{"z_syscall_linux_*.txt", "z_syscall_linux_arm64.txt", "arm64"},
+		{"case_*.txt", "case_1.txt", "1"},
+		{"case_?.txt", "case_1.txt", "case_1"},
+		{"*", "case", "case"},
+		{"*.txt", ".txt", ""},
+	} {
+		if got := platformName(c.pattern, c.name); got != c.want {
+			t.Errorf("%s %s: got %q, want %q", c.pattern, c.name, got, c.want)
+		}
+	}
+}
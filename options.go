@@ -0,0 +1,110 @@
+package testingfiles
+
+import "io/fs"
+
+// An Option configures a function of the package.
+// Options which are meaningless to a function are ignored, except by compare and streaming functions
+// which fail with errors.ErrUnsupported.
+type Option func(*options)
+
+type options struct {
+	mode          fs.FileMode              // permissions of written files
+	verify        bool                     // supplements are verified before writing
+	rename        func(name string) string // name of the supplement of a file
+	keepOrder     bool                     // lines are written in the order of the files
+	dedupe        bool                     // duplicated lines of a file are counted once
+	multiset      bool                     // duplicated lines of a file are counted
+	memoryLimit   int                      // size of the lines sorted in memory when streaming
+	workers       int                      // number of files read concurrently
+	key           func(line string) string // key comparing lines
+	dryRun        bool                     // no file is written
+	report        *Report                  // description of the run
+	dest          string                   // directory of output files
+	skipBlank     bool                     // blank lines are ignored
+	comments      []string                 // prefixes of ignored comment lines
+	trimSpace     bool                     // white space around lines is removed
+	foldCase      bool                     // lines are compared ignoring case
+	keepHeader    bool                     // leading comments are written
+	stripBOM      bool                     // UTF-8 byte order mark is removed
+	decodeUTF16   bool                     // UTF-16 is decoded
+	normalize     func([]byte) []byte      // normalization of lines
+	trailingSpace bool                     // white space at the end of lines is ignored by comparisons
+	collapseSpace bool                     // runs of white space compare like a space
+	allSpace      bool                     // white space is ignored by comparisons
+	blankLines    bool                     // blank lines are ignored by comparisons
+	unordered     bool                     // order of lines is ignored by comparisons
+	blockStart    string                   // prefix of the line starting a block of unordered lines
+	blockEnd      string                   // prefix of the line ending a block of unordered lines
+}
+
+// newOptions returns the defaults updated with opts.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// FileMode sets the permissions of written files. By default, existing files keep their permissions
+// and new files are created with 0644.
+func FileMode(mode fs.FileMode) Option {
+	return func(o *options) {
+		o.mode = mode.Perm()
+	}
+}
+
+// VerifySupplements checks that each supplement and the baseline recompose the original file
+// before any file is written.
+func VerifySupplements() Option {
+	return func(o *options) {
+		o.verify = true
+	}
+}
+
+// SupplementName writes the supplement of a file to the name returned by rename instead of overwriting it.
+// rename receives the path of the file.
+func SupplementName(rename func(name string) string) Option {
+	return func(o *options) {
+		o.rename = rename
+	}
+}
+
+// KeepOrder keeps lines in the order of the first file for an intersection and in the order of each file
+// for a supplement. Otherwise, lines are sorted.
+func KeepOrder() Option {
+	return func(o *options) {
+		o.keepOrder = true
+	}
+}
+
+// Dedupe applies set semantics: only the first copy of a duplicated line of a file is used.
+func Dedupe() Option {
+	return func(o *options) {
+		o.dedupe, o.multiset = true, false
+	}
+}
+
+// Multiset applies multiset semantics: an intersection keeps a line as many times as the file
+// holding the fewest copies of it, and a supplement removes a line as many times as the baseline holds it.
+// It is the default, whether lines are sorted or in file order, and cancels Dedupe.
+func Multiset() Option {
+	return func(o *options) {
+		o.dedupe, o.multiset = false, true
+	}
+}
+
+// Workers sets the number of files read concurrently. The default is runtime.GOMAXPROCS(0).
+func Workers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// Destination writes output files in dir instead of the directory of the input files.
+// Missing directories are created.
+func Destination(dir string) Option {
+	return func(o *options) {
+		o.dest = dir
+	}
+}
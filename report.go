@@ -0,0 +1,89 @@
+package testingfiles
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Report describes a run of ExtractCommon or CreateSupplements. It is filled when ReportTo is used.
+type Report struct {
+	Files     []FileReport `json:"files"`               // input files in the order of filepath.Glob
+	Common    []string     `json:"common"`              // lines of the intersection or of the baseline
+	EmptiedBy string       `json:"emptiedBy,omitempty"` // first file leaving the intersection empty
+	Written   []string     `json:"written"`             // files written, or which would be written by a dry run
+	Changed   []string     `json:"changed"`             // written files whose content is new or differs
+}
+
+// FileReport describes an input file.
+type FileReport struct {
+	Name    string `json:"name"`
+	Lines   int    `json:"lines"`   // lines of the file
+	Removed int    `json:"removed"` // lines which are not in the intersection, or removed by the baseline
+}
+
+// DryRun computes the result and fills the report without writing any file.
+func DryRun() Option {
+	return func(o *options) {
+		o.dryRun = true
+	}
+}
+
+// ReportTo fills r with the description of the run. r is reset first.
+func ReportTo(r *Report) Option {
+	return func(o *options) {
+		o.report = r
+	}
+}
+
+// newReport resets the report, if any, with the files fl and the number of their lines when known.
+func (o *options) newReport(fl []string, lists [][]string) *Report {
+	if o.report == nil {
+		return nil
+	}
+	*o.report = Report{Files: make([]FileReport, len(fl))}
+	for i, f := range fl {
+		o.report.Files[i].Name = f
+		if lists != nil {
+			o.report.Files[i].Lines = len(lists[i])
+		}
+	}
+	return o.report
+}
+
+// emptiedBy returns the index of the first list leaving the intersection of lists empty.
+func (o *options) emptiedBy(lists [][]string) int {
+	keys := o.keySet(lists[0])
+	for i, lines := range lists[1:] {
+		other := o.keySet(lines)
+		keys = slices.DeleteFunc(keys, func(k string) bool {
+			_, found := slices.BinarySearch(other, k)
+			return !found
+		})
+		if len(keys) == 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// output writes lines separated by a new line to the file name like WriteFile unless DryRun is used,
+// and reports it.
+func (o *options) output(name string, lines []string) error {
+	content := []byte(strings.Join(lines, "\n"))
+	if r := o.report; r != nil {
+		r.Written = append(r.Written, name)
+		if old, err := os.ReadFile(name); err != nil || !bytes.Equal(old, content) {
+			r.Changed = append(r.Changed, name)
+		}
+	}
+	if o.dryRun {
+		return nil
+	}
+	return writeFile(name, o, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+}
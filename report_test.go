@@ -0,0 +1,79 @@
+package testingfiles
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExtractCommon_dryrun(t *testing.T) {
+	w := t.TempDir()
+	if err := os.CopyFS(w, os.DirFS(path)); err != nil {
+		t.Fatal(err)
+	}
+	var r Report
+	if err := ExtractCommon(w, globf, commonf, DryRun(), ReportTo(&r)); err != nil {
+		t.Fatal(err)
+	}
+	common := filepath.Join(w, commonf)
+	if _, err := os.Stat(common); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+	if !slices.Equal(r.Common, []string{commonline}) {
+		t.Errorf("got %q, want %q", r.Common, commonline)
+	}
+	want := []FileReport{
+		{Name: filepath.Join(w, "case_1.txt"), Lines: 6, Removed: 5},
+		{Name: filepath.Join(w, "case_2.txt"), Lines: 4, Removed: 3},
+	}
+	if !slices.Equal(r.Files, want) {
+		t.Errorf("got %v, want %v", r.Files, want)
+	}
+	if !slices.Equal(r.Written, []string{common}) || !slices.Equal(r.Changed, []string{common}) {
+		t.Errorf("got written %v and changed %v, want %s", r.Written, r.Changed, common)
+	}
+	// Unchanged baseline
+	if err := ExtractCommon(w, globf, commonf); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractCommon(w, globf, commonf, DryRun(), ReportTo(&r)); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Changed) != 0 {
+		t.Errorf("got %v changed, want none", r.Changed)
+	}
+	// Supplements
+	if err := CreateSupplements(w, globf, commonf, DryRun(), ReportTo(&r)); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Changed) != 2 || r.Files[0].Removed != 1 || r.Files[1].Lines != 4 {
+		t.Errorf("got %+v", r)
+	}
+	lines, err := readLines(filepath.Join(w, "case_1.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 6 {
+		t.Errorf("dry run wrote %q", lines)
+	}
+}
+
+func TestExtractCommon_emptiedby(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{
+		"case_1.txt": "a\nb\n",
+		"case_2.txt": "b\nc\n",
+		"case_3.txt": "c\nd\n",
+		"case_4.txt": "e\n",
+	})
+	var r Report
+	if err := ExtractCommon(w, globf, commonf, ReportTo(&r)); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got %v, want %v", err, fs.ErrNotExist)
+	}
+	if want := filepath.Join(w, "case_3.txt"); r.EmptiedBy != want {
+		t.Errorf("got %s, want %s", r.EmptiedBy, want)
+	}
+}
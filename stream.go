@@ -0,0 +1,487 @@
+package testingfiles
+
+import (
+	"bufio"
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// defaultMemoryLimit is the size of the lines sorted in memory by default when streaming.
+const defaultMemoryLimit = 64 << 20
+
+// mergeFanIn is the number of runs merged at once which bounds the number of open files.
+const mergeFanIn = 64
+
+// ExtractCommonStream behaves like ExtractCommon but holds a bounded amount of lines in memory.
+// Each file is sorted using an external merge sort into temporary files, then all files are merged
+// in a single pass. Memory used to sort is set by MemoryLimit.
+// Lines are always sorted and KeepOrder is ignored. Options which are not supported, like Key, DryRun or
+// the encoding options, fail with errors.ErrUnsupported.
+func ExtractCommonStream(contextFilesPath, globf, commonf string, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.streamable(); err != nil {
+		return err
+	}
+	fl, err := globFiles(contextFilesPath, globf)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp("", "testingfiles")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	sorted, err := o.sortFiles(fl, tmp)
+	if err != nil {
+		return err
+	}
+	cs, err := openCursors(sorted)
+	defer closeCursors(cs)
+	if err != nil {
+		return err
+	}
+	n := 0
+	err = writeFile(o.destination(contextFilesPath, commonf), o, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		err := intersectCursors(cs, func(l string) error {
+			if n != 0 {
+				bw.WriteByte('\n')
+			}
+			n++
+			_, err := bw.WriteString(l)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			log.Printf("intersection is empty")
+			return os.ErrNotExist
+		}
+		return bw.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("intersection has %v line(s) written to %s", n, commonf)
+	return nil
+}
+
+// CreateSupplementsStream behaves like CreateSupplements but holds a bounded amount of lines in memory.
+// The baseline and each file are sorted like ExtractCommonStream does and merged to produce the supplement.
+// All supplements are stored in temporary files before any file is written.
+// Lines are always sorted and KeepOrder is ignored. A merge always recomposes the original file and
+// VerifySupplements is not needed. Options are supported like ExtractCommonStream does.
+func CreateSupplementsStream(contextFilesPath, globf, baselinef string, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.streamable(); err != nil {
+		return err
+	}
+	fl, err := globFiles(contextFilesPath, globf)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp("", "testingfiles")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	baseline, err := o.sortFile(filepath.Join(contextFilesPath, baselinef), tmp)
+	if err != nil {
+		return err
+	}
+	supplements := make([]string, len(fl))
+	counts := make([]int, len(fl))
+	var errs []error
+	for i, f := range fl {
+		sorted, err := o.sortFile(f, tmp)
+		if err != nil {
+			return err
+		}
+		supplements[i], counts[i], err = supplementFile(f, sorted, baseline, tmp)
+		var missing *MissingLinesError
+		if errors.As(err, &missing) {
+			errs = append(errs, err)
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+	}
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+	for i, f := range fl {
+		if o.rename != nil {
+			f = o.rename(f)
+		}
+		f = o.destination(filepath.Dir(f), filepath.Base(f))
+		r, err := os.Open(supplements[i])
+		if err != nil {
+			return err
+		}
+		err = writeFile(f, o, func(w io.Writer) error {
+			_, err := io.Copy(w, r)
+			return err
+		})
+		r.Close()
+		if err != nil {
+			return err
+		}
+		log.Printf("%s has %v line(s)", f, counts[i])
+	}
+	return nil
+}
+
+// MemoryLimit sets the size in bytes of the lines sorted in memory by streaming functions.
+// The default is 64 MiB. Larger files are sorted in runs merged afterwards.
+func MemoryLimit(n int) Option {
+	return func(o *options) {
+		o.memoryLimit = n
+	}
+}
+
+// streamable returns an error wrapping errors.ErrUnsupported which lists the options set which streaming
+// functions do not support.
+func (o *options) streamable() error {
+	var unsupported []string
+	for _, opt := range []struct {
+		set  bool
+		name string
+	}{
+		{o.key != nil, "Key"},
+		{o.decodes(), "encoding options"},
+		{o.dryRun, "DryRun"},
+		{o.report != nil, "ReportTo"},
+		{o.keepHeader, "KeepHeader"},
+	} {
+		if opt.set {
+			unsupported = append(unsupported, opt.name)
+		}
+	}
+	if len(unsupported) != 0 {
+		return fmt.Errorf("%w: %s when streaming", errors.ErrUnsupported, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// sortFiles sorts every file of fl into a temporary file of dir and returns their names in the same order.
+// Files are sorted concurrently like loadFeatures does.
+func (o *options) sortFiles(fl []string, dir string) ([]string, error) {
+	sorted := make([]string, len(fl))
+	err := o.forEachFile(fl, func(i int, f string) (err error) {
+		sorted[i], err = o.sortFile(f, dir)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sorted, nil
+}
+
+// sortFile sorts the lines of filename into a temporary file of dir and returns its name.
+// Lines are sorted in runs of the size of the memory limit which are merged.
+// Lines are filtered like orderedFeatures does and it fails on an empty file.
+// With Dedupe, only one copy of every line is kept.
+func (o *options) sortFile(filename, dir string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	limit := o.memoryLimit
+	if limit <= 0 {
+		limit = defaultMemoryLimit
+	}
+	var runs []string
+	var lines []string
+	size, n := 0, 0
+	flush := func() error {
+		slices.Sort(lines)
+		if o.dedupe {
+			lines = slices.Compact(lines)
+		}
+		run, err := writeRun(dir, func(bw *bufio.Writer) error {
+			for _, l := range lines {
+				if _, err := bw.WriteString(l + "\n"); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+		lines, size = lines[:0], 0
+		return nil
+	}
+	r := bufio.NewReader(f)
+	for {
+		l, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		if err == io.EOF {
+			// A last empty line is removed as it is usually an artifact
+			if strings.TrimSpace(l) == "" {
+				break
+			}
+		} else {
+			l = l[:len(l)-1]
+		}
+		if l, ok := o.filterLine(l); ok {
+			lines = append(lines, l)
+			n++
+			if size += len(l); size >= limit {
+				if err := flush(); err != nil {
+					return "", err
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if n == 0 {
+		log.Printf("%s is empty", filename)
+		return "", fs.ErrNotExist
+	}
+	if len(lines) != 0 || len(runs) == 0 {
+		if err := flush(); err != nil {
+			return "", err
+		}
+	}
+	log.Printf("%s has %v lines", filename, n)
+	if len(runs) == 1 {
+		return runs[0], nil
+	}
+	return mergeRuns(runs, dir, o.dedupe)
+}
+
+// writeRun writes a temporary file of dir using write and returns its name.
+func writeRun(dir string, write func(bw *bufio.Writer) error) (string, error) {
+	f, err := os.CreateTemp(dir, "run*")
+	if err != nil {
+		return "", err
+	}
+	bw := bufio.NewWriter(f)
+	if err = write(bw); err == nil {
+		err = bw.Flush()
+	}
+	if errc := f.Close(); err == nil {
+		err = errc
+	}
+	return f.Name(), err
+}
+
+// mergeRuns merges the sorted runs into a temporary file of dir and removes them.
+// Runs are merged by passes of at most mergeFanIn runs.
+func mergeRuns(runs []string, dir string, dedupe bool) (string, error) {
+	for len(runs) > mergeFanIn {
+		var merged []string
+		for i := 0; i < len(runs); i += mergeFanIn {
+			end := i + mergeFanIn
+			if end > len(runs) {
+				end = len(runs)
+			}
+			run, err := mergeBatch(runs[i:end], dir, dedupe)
+			if err != nil {
+				return "", err
+			}
+			merged = append(merged, run)
+		}
+		runs = merged
+	}
+	return mergeBatch(runs, dir, dedupe)
+}
+
+// mergeBatch merges the sorted runs, which are all opened, into a temporary file of dir and removes them.
+func mergeBatch(runs []string, dir string, dedupe bool) (string, error) {
+	cs, err := openCursors(runs)
+	defer func() {
+		closeCursors(cs)
+		for _, r := range runs {
+			_ = os.Remove(r)
+		}
+	}()
+	if err != nil {
+		return "", err
+	}
+	h := cursorHeap(slices.DeleteFunc(slices.Clone(cs), (*cursor).done))
+	heap.Init(&h)
+	return writeRun(dir, func(bw *bufio.Writer) error {
+		last, first := "", true
+		for len(h) != 0 {
+			c := h[0]
+			if !dedupe || first || c.line != last {
+				if _, err := bw.WriteString(c.line + "\n"); err != nil {
+					return err
+				}
+			}
+			last, first = c.line, false
+			if err := c.next(); err != nil {
+				return err
+			}
+			if c.done() {
+				heap.Pop(&h)
+			} else {
+				heap.Fix(&h, 0)
+			}
+		}
+		return nil
+	})
+}
+
+// intersectCursors calls found with the lines read by every cursor in a single pass over all of them.
+// A line read n times by every cursor is found n times.
+func intersectCursors(cs []*cursor, found func(l string) error) error {
+	h := cursorHeap(slices.Clone(cs))
+	heap.Init(&h)
+	for len(h) == len(cs) {
+		// Cursors holding the smallest line
+		var same []*cursor
+		for len(h) != 0 && (len(same) == 0 || h[0].line == same[0].line) {
+			same = append(same, heap.Pop(&h).(*cursor))
+		}
+		if len(same) == len(cs) {
+			if err := found(same[0].line); err != nil {
+				return err
+			}
+		}
+		for _, c := range same {
+			if err := c.next(); err != nil {
+				return err
+			}
+			if !c.done() {
+				heap.Push(&h, c)
+			}
+		}
+	}
+	return nil
+}
+
+// supplementFile writes the lines of the sorted file of name which are not in the sorted baseline into
+// a temporary file of dir. It returns its name and its number of lines.
+// It fails with a *MissingLinesError if lines of the baseline are missing from the file.
+func supplementFile(name, sorted, baseline, dir string) (supplement string, n int, err error) {
+	cs, err := openCursors([]string{sorted, baseline})
+	defer closeCursors(cs)
+	if err != nil {
+		return "", 0, err
+	}
+	f, b := cs[0], cs[1]
+	var missing []string
+	supplement, err = writeRun(dir, func(bw *bufio.Writer) error {
+		write := func(l string) error {
+			if n != 0 {
+				bw.WriteByte('\n')
+			}
+			n++
+			_, err := bw.WriteString(l)
+			return err
+		}
+		for !b.done() {
+			for !f.done() && f.line < b.line {
+				if err := write(f.line); err != nil {
+					return err
+				}
+				if err := f.next(); err != nil {
+					return err
+				}
+			}
+			if f.done() || f.line != b.line {
+				missing = append(missing, b.line)
+			} else if err := f.next(); err != nil {
+				return err
+			}
+			if err := b.next(); err != nil {
+				return err
+			}
+		}
+		// Lines after the last common feature
+		for !f.done() {
+			if err := write(f.line); err != nil {
+				return err
+			}
+			if err := f.next(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil && len(missing) != 0 {
+		err = &MissingLinesError{File: name, Lines: missing}
+	}
+	return supplement, n, err
+}
+
+// cursor reads the lines of a sorted temporary file. Every line ends with a new line.
+type cursor struct {
+	f    *os.File
+	r    *bufio.Reader
+	line string
+	eof  bool
+}
+
+// next reads the next line.
+func (c *cursor) next() error {
+	l, err := c.r.ReadString('\n')
+	if err == io.EOF && l == "" {
+		c.eof = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.line = l[:len(l)-1]
+	return nil
+}
+
+func (c *cursor) done() bool {
+	return c.eof
+}
+
+// openCursors opens a cursor on every file and reads its first line.
+// Opened cursors are returned with the error.
+func openCursors(names []string) ([]*cursor, error) {
+	var cs []*cursor
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return cs, err
+		}
+		c := &cursor{f: f, r: bufio.NewReader(f)}
+		cs = append(cs, c)
+		if err = c.next(); err != nil {
+			return cs, err
+		}
+	}
+	return cs, nil
+}
+
+func closeCursors(cs []*cursor) {
+	for _, c := range cs {
+		_ = c.f.Close()
+	}
+}
+
+// cursorHeap orders cursors by their current line.
+type cursorHeap []*cursor
+
+func (h cursorHeap) Len() int           { return len(h) }
+func (h cursorHeap) Less(i, j int) bool { return h[i].line < h[j].line }
+func (h cursorHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *cursorHeap) Push(x any)        { *h = append(*h, x.(*cursor)) }
+func (h *cursorHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
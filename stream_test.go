@@ -0,0 +1,146 @@
+package testingfiles
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractCommonStream(t *testing.T) {
+	for _, limit := range []int{0, 1, 100} {
+		w := t.TempDir()
+		if err := os.CopyFS(w, os.DirFS(path)); err != nil {
+			t.Fatal(err)
+		}
+		if err := ExtractCommonStream(w, globf, commonf, MemoryLimit(limit)); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(filepath.Join(w, commonf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != commonline {
+			t.Errorf("limit %d: got %s, want %s", limit, b, commonline)
+		}
+	}
+}
+
+func TestExtractCommonStream_duplicates(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{
+		"case_1.txt": "c\na\nb\na\na\n",
+		"case_2.txt": "a\nc\na\nb\nb\n  ",
+		"case_3.txt": "b\na\na\nc\nd\n",
+	})
+	for _, tc := range []struct {
+		opts []Option
+		want string
+	}{
+		{[]Option{MemoryLimit(1)}, "a\na\nb\nc"},
+		{[]Option{MemoryLimit(1), Dedupe()}, "a\nb\nc"},
+	} {
+		if err := ExtractCommonStream(w, globf, commonf, tc.opts...); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(filepath.Join(w, commonf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.want {
+			t.Errorf("got %q, want %q", b, tc.want)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(w, "case_4.txt"), []byte("e"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractCommonStream(w, globf, "not_created"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+	if _, err := os.Stat(filepath.Join(w, "not_created")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+	if err := os.WriteFile(filepath.Join(w, "case_4.txt"), nil, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractCommonStream(w, globf, "not_created"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestCreateSupplementsStream(t *testing.T) {
+	want := t.TempDir()
+	if err := os.CopyFS(want, os.DirFS(path)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractCommon(want, globf, commonf); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateSupplements(want, globf, commonf); err != nil {
+		t.Fatal(err)
+	}
+	w := t.TempDir()
+	if err := os.CopyFS(w, os.DirFS(path)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractCommonStream(w, globf, commonf, MemoryLimit(100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateSupplementsStream(w, globf, commonf, MemoryLimit(100)); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"case_1.txt", "case_2.txt"} {
+		if err := FileCompare(filepath.Join(w, f), filepath.Join(want, f)); err != nil {
+			t.Errorf("%s: %v", f, err)
+		}
+	}
+	// Common line is missing
+	if err := os.WriteFile(filepath.Join(w, "case_3.txt"), []byte("a"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	err := CreateSupplementsStream(w, globf, commonf, SupplementName(func(name string) string {
+		return name + ".supp"
+	}))
+	if err == nil {
+		t.Fatal("unexpected success")
+	}
+	if matches, _ := filepath.Glob(filepath.Join(w, "*.supp")); len(matches) != 0 {
+		t.Errorf("got %v written, want none", matches)
+	}
+}
+
+// Runs are merged in several passes when they exceed the fan-in
+func TestExtractCommonStream_passes(t *testing.T) {
+	w := t.TempDir()
+	var b strings.Builder
+	for i := 3 * mergeFanIn; i > 0; i-- {
+		fmt.Fprintf(&b, "line %04d\n", i)
+	}
+	writeFiles(t, w, map[string]string{"case_1.txt": b.String(), "case_2.txt": b.String()})
+	if err := ExtractCommonStream(w, globf, commonf, MemoryLimit(1), Destination(filepath.Join(w, "dest"))); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readLines(filepath.Join(w, "dest", commonf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3*mergeFanIn || !slices.IsSorted(got) {
+		t.Errorf("got %d lines, sorted %v, want %d sorted lines", len(got), slices.IsSorted(got), 3*mergeFanIn)
+	}
+}
+
+func TestExtractCommonStream_unsupported(t *testing.T) {
+	w := t.TempDir()
+	for _, opt := range []Option{Key(strings.ToUpper), KeepHeader(), StripBOM(), DryRun(), ReportTo(&Report{})} {
+		if err := ExtractCommonStream(w, globf, commonf, opt); !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("got %v, want %v", err, errors.ErrUnsupported)
+		}
+		if err := CreateSupplementsStream(w, globf, commonf, opt); !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("got %v, want %v", err, errors.ErrUnsupported)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package testingfiles
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// UnorderedLines compares contents as multisets of lines: the order of lines is ignored and a line must be
+// found as many times in got as in want. Differences are reported by an *UnorderedError.
+func UnorderedLines() Option {
+	return func(o *options) {
+		o.unordered = true
+		o.blockStart, o.blockEnd = "", ""
+	}
+}
+
+// UnorderedBlocks ignores the order of lines only within blocks. A block holds the lines following a line
+// starting with start up to the next line starting with end. Delimiters and lines outside blocks keep their order.
+func UnorderedBlocks(start, end string) Option {
+	return func(o *options) {
+		o.unordered = true
+		o.blockStart, o.blockEnd = start, end
+	}
+}
+
+// UnorderedError reports the differences of contents compared without the order of their lines.
+type UnorderedError struct {
+	Missing    []string // sorted lines of want missing from got
+	Unexpected []string // sorted lines of got which are not in want
+	Line       int      // first line of the raw got content out of order when no line is missing or unexpected
+}
+
+func (e *UnorderedError) Error() string {
+	if len(e.Missing) == 0 && len(e.Unexpected) == 0 {
+		return fmt.Sprintf("line %d is out of order", e.Line)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d line(s) missing, %d unexpected line(s):", len(e.Missing), len(e.Unexpected))
+	for _, l := range e.Missing {
+		fmt.Fprintf(&b, "\n\t-%s", l)
+	}
+	for _, l := range e.Unexpected {
+		fmt.Fprintf(&b, "\n\t+%s", l)
+	}
+	return b.String()
+}
+
+// sortBlocks sorts the lines of every block in place. Without blocks, all lines are sorted.
+func (o *options) sortBlocks(lines []string) {
+	if o.blockStart == "" && o.blockEnd == "" {
+		slices.Sort(lines)
+		return
+	}
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], o.blockStart) {
+			continue
+		}
+		j := i + 1
+		for j < len(lines) && !strings.HasPrefix(lines[j], o.blockEnd) {
+			j++
+		}
+		slices.Sort(lines[i+1 : j])
+		// The line ending the block might start the next one
+		i = j - 1
+	}
+}
+
+// compareUnordered compares the lines of got and want ignoring their order as set by the options.
+// The line out of order is located in the raw got content.
+func (o *options) compareUnordered(gott, wantt text) error {
+	got, want := splitLines(string(gott.data)), splitLines(string(wantt.data))
+	o.sortBlocks(got)
+	o.sortBlocks(want)
+	if slices.Equal(got, want) {
+		return nil
+	}
+	e := &UnorderedError{}
+	e.Unexpected, e.Missing = DiffLines(want, got)
+	if len(e.Missing) == 0 && len(e.Unexpected) == 0 {
+		k := 0
+		for got[k] == want[k] {
+			k++
+		}
+		e.Line, _ = gott.position(gott.off[gott.lineStart(k)])
+	}
+	return e
+}
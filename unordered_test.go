@@ -0,0 +1,116 @@
+package testingfiles
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFileCompare_unordered(t *testing.T) {
+	w := t.TempDir()
+	want := filepath.Join(w, "want")
+	if err := os.WriteFile(want, []byte("[a]\nx=1\ny=2\ny=2\n[b]\nz=3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		got  string
+		opts []Option
+	}{
+		{"lines", "y=2\nz=3\n[b]\ny=2\n[a]\nx=1\n", []Option{UnorderedLines()}},
+		{"blocks", "[a]\ny=2\nx=1\ny=2\n[b]\nz=3\n", []Option{UnorderedBlocks("[", "[")}},
+		{"space", "[a] \ny=2\nx=1\n\ny=2\n[b]\nz=3", []Option{UnorderedLines(), IgnoreTrailingSpace(), IgnoreBlankLines()}},
+	} {
+		got := filepath.Join(w, "got")
+		if err := os.WriteFile(got, []byte(tc.got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := FileCompare(got, want); err == nil {
+			t.Errorf("%s: unexpected success without option", tc.name)
+		}
+		if err := FileCompare(got, want, tc.opts...); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if err := BufferCompare(bytes.NewBufferString(tc.got), want, tc.opts...); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+}
+
+func TestFileCompare_unorderedError(t *testing.T) {
+	w := t.TempDir()
+	want := filepath.Join(w, "want")
+	if err := os.WriteFile(want, []byte("[a]\nx=1\ny=2\ny=2\n[b]\nz=3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name       string
+		got        string
+		opts       []Option
+		missing    []string
+		unexpected []string
+		line       int
+	}{
+		{"lines", "y=2\nz=3\n[b]\nw=0\n[a]\nx=1\n", []Option{UnorderedLines()}, []string{"y=2"}, []string{"w=0"}, 0},
+		{"blocks", "[a]\ny=2\nx=1\n[b]\ny=2\nz=3\n", []Option{UnorderedBlocks("[", "[")}, nil, nil, 4},
+		{"outside", "[b]\nz=3\n[a]\nx=1\ny=2\ny=2\n", []Option{UnorderedBlocks("[", "[")}, nil, nil, 1},
+	} {
+		err := BufferCompare(bytes.NewBufferString(tc.got), want, tc.opts...)
+		var u *UnorderedError
+		if !errors.As(err, &u) {
+			t.Fatalf("%s: got %v, want an *UnorderedError", tc.name, err)
+		}
+		if !slices.Equal(u.Missing, tc.missing) || !slices.Equal(u.Unexpected, tc.unexpected) || u.Line != tc.line {
+			t.Errorf("%s: got %q %q %d, want %q %q %d", tc.name, u.Missing, u.Unexpected, u.Line,
+				tc.missing, tc.unexpected, tc.line)
+		}
+	}
+}
+
+// Every block is sorted when the line ending a block starts the next one
+func TestFileCompare_unorderedBlocks(t *testing.T) {
+	want := filepath.Join(t.TempDir(), "want")
+	if err := os.WriteFile(want, []byte("[a]\nx\ny\n[b]\nz\nw\n[c]\np\nq\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := "[a]\ny\nx\n[b]\nw\nz\n[c]\nq\np\n"
+	if err := BufferCompare(bytes.NewBufferString(got), want, UnorderedBlocks("[", "[")); err != nil {
+		t.Error(err)
+	}
+	moved := "[a]\ny\n[b]\nx\nw\nz\n[c]\nq\np\n"
+	if err := BufferCompare(bytes.NewBufferString(moved), want, UnorderedBlocks("[", "[")); err == nil {
+		t.Error("line moved to another block is not reported")
+	}
+}
+
+// Lines out of order are located in the raw got content
+func TestFileCompare_unorderedLine(t *testing.T) {
+	want := filepath.Join(t.TempDir(), "want")
+	if err := os.WriteFile(want, []byte("[a]\nx\n[b]\ny\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := "\xEF\xBB\xBF\n\n[b]\ny\n\n[a]\nx\n"
+	err := BufferCompare(bytes.NewBufferString(got), want, UnorderedBlocks("[", "["), IgnoreBlankLines(), StripBOM())
+	var u *UnorderedError
+	if !errors.As(err, &u) {
+		t.Fatalf("got %v, want an *UnorderedError", err)
+	}
+	if u.Line != 3 {
+		t.Errorf("got line %d, want 3", u.Line)
+	}
+}
+
+func TestUnorderedError_Error(t *testing.T) {
+	e := &UnorderedError{Missing: []string{"a"}, Unexpected: []string{"b", "c"}}
+	if got, want := e.Error(), "1 line(s) missing, 2 unexpected line(s):\n\t-a\n\t+b\n\t+c"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	e = &UnorderedError{Line: 3}
+	if got := e.Error(); !strings.Contains(got, "line 3") {
+		t.Errorf("got %q, want line 3", got)
+	}
+}
@@ -3,6 +3,7 @@ package testingfiles
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -33,73 +34,54 @@ func OutputDir(s string) {
 
 // StringToFile produces a file named fname with the content
 //
-// Deprecated: use the os package [os] instead; see [WriteFile].
+// Deprecated: use [WriteFile] which returns an error instead of panicking.
 func StringToFile(fname string, content []byte) {
-	wfile, err := os.Create(fname)
-	if err != nil {
-		panic(err)
-	}
-	defer func() {
-		err = wfile.Close()
-		if err != nil {
-			panic(err)
-		}
-	}()
-
-	_, err = wfile.Write(content)
+	err := writeGolden(fname, newOptions(nil), func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
 	if err != nil {
 		panic(err)
 	}
 }
 
-// BufferToFile produces a file named fname with the content
+// BufferToFile produces a file named fname with the content.
+// It panics on error. [WriteBuffer] returns the error.
 func BufferToFile(fname string, content *bytes.Buffer) {
-	wfile, err := os.Create(fname)
-	if err != nil {
-		panic(err)
-	}
-	defer func() {
-		err = wfile.Close()
-		if err != nil {
-			panic(err)
-		}
-	}()
-
-	_, err = wfile.Write(content.Bytes())
+	err := writeGolden(fname, newOptions(nil), func(w io.Writer) error {
+		_, err := w.Write(content.Bytes())
+		return err
+	})
 	if err != nil {
 		panic(err)
 	}
 }
 
-// ReadCloserToFile creates a file named fname with the content
+// ReadCloserToFile creates a file named fname with the content which is closed.
+//
+// Deprecated: use [WriteReadCloser] which it calls.
 func ReadCloserToFile(fname string, content io.ReadCloser) error {
-	wfile, err := os.Create(fname)
-	if err != nil {
-		panic(err)
-	}
-	defer func() {
-		err = wfile.Close()
-		if err != nil {
-			panic(err)
-		}
-	}()
-	c, err := io.ReadAll(content)
-	if err != nil {
-		panic(err)
-	}
-	n, err := wfile.Write(c)
-	if err != nil {
-		panic(err)
-	}
-	if len(c) != n {
-		return fmt.Errorf("file %s is missing %d bytes", fname, len(c)-n)
-	}
-	return nil
+	return WriteReadCloser(fname, content)
 }
 
 // FileCompare checks large outputs of a test when a file storage is more convenient or required.
 // Names of the files to compare are passed as arguments and searched in the working directory.
-func FileCompare(got, want string) error {
+// With options like StripBOM or CollapseSpace, contents are transformed before comparison and
+// a *MismatchError locates the first difference in the raw contents. Other options, which are the ones
+// of extraction functions, fail with errors.ErrUnsupported.
+func FileCompare(got, want string, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.compareOptions(); err != nil {
+		return err
+	}
+	useGolden(want)
+	if o.transforms() {
+		gotb, err := os.ReadFile(got)
+		if err != nil {
+			return err
+		}
+		return o.compareDecoded(gotb, want)
+	}
 	filew, err := os.Open(want)
 	if err != nil {
 		return err
@@ -148,11 +130,18 @@ func FileCompare(got, want string) error {
 }
 
 // BufferCompare compares the buffer to a file.
-// If an error occurs, got file is created and the error is returned.
-// First char in the got file is the erroneous char.
+// If an error occurs, got file is created in the artifact directory and the error is returned.
+// The got file holds the complete buffer and its metadata the index of the first difference.
 // If identical, nil is returned.
 // First byte index is 0
-func BufferCompare(got *bytes.Buffer, want string) error {
+// The buffer is read up to the first difference. Options are the ones of FileCompare and the buffer
+// is then emptied.
+func BufferCompare(got *bytes.Buffer, want string, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.compareOptions(); err != nil {
+		return err
+	}
+	useGolden(want)
 	wantf, err := os.Open(want)
 	if err != nil {
 		return err
@@ -163,6 +152,12 @@ func BufferCompare(got *bytes.Buffer, want string) error {
 
 	// Build got filename.
 	fileg := callerName("buffercomparedefault")
+	caller := callerFunc(fileg)
+	all := got.Bytes() // Unread content is kept for the got file
+	if o.transforms() {
+		got.Reset()
+		return o.compareDump(all, want, caller)
+	}
 	b1 := make([]byte, 1)
 	var b2 byte
 	index := 0          // Index in file to locate error
@@ -186,16 +181,22 @@ func BufferCompare(got *bytes.Buffer, want string) error {
 						return nil
 					}
 					// Occurs when original buffer is used
-					return fmt.Errorf("got %v and last byte %q is missing", err, b1[0])
+					err = fmt.Errorf("got %v and last byte %q is missing", err, b1[0])
+				} else {
+					err = fmt.Errorf("%s : got %v, want %q at %d. Buffer is missing %d",
+						fileg, err, b1[0], index, wantfInfo.Size()-int64(index))
 				}
-				return fmt.Errorf("%s : got %v, want %q at %d. Buffer is missing %d",
-					fileg, err, b1[0], index, wantfInfo.Size()-int64(index))
+				dumpGot(Artifact{Caller: caller, Want: want, Offset: int64(index), Error: err.Error()},
+					bytes.NewReader(all))
+				return err
 			}
 
 			if b1[0] != b2 {
 				_ = got.UnreadByte() // recover the erroneous char
-				BufferToFile(fmt.Sprintf("got_%s", fileg), got)
-				return fmt.Errorf("got %q, want %q at %d", b2, b1, index)
+				err = fmt.Errorf("got %q, want %q at %d", b2, b1, index)
+				dumpGot(Artifact{Caller: caller, Want: want, Offset: int64(index), Error: err.Error()},
+					bytes.NewReader(all))
+				return err
 			}
 			index++
 		} else if err != nil && err != io.EOF {
@@ -207,20 +208,29 @@ func BufferCompare(got *bytes.Buffer, want string) error {
 	// If EOF is not returned, buffer is longer than the file which is exhausted.
 	if err != io.EOF {
 		_ = got.UnreadByte()
-		BufferToFile(fmt.Sprintf("got_%s", fileg), got)
-		return fmt.Errorf("got buffer is too long by %d", got.Len())
+		err = fmt.Errorf("got buffer is too long by %d", got.Len())
+		dumpGot(Artifact{Caller: caller, Want: want, Offset: int64(index), Error: err.Error()},
+			bytes.NewReader(all))
+		return err
 	}
 	return nil
 }
 
 // ReadCloserCompare compares a ReadCloser to a file.
-// If an error occurs, got file is created and the error is returned.
-// Last read byte is absent from the got file but available in the error message.
+// If an error occurs, got file is created in the artifact directory and the error is returned.
+// Read bytes are kept to have the complete response in the got file.
+// Its metadata holds the index of the first difference.
 // If identical, nil is returned.
 // Logic and method are identical to *buffer.Bytes but duplicating the code avoids ReadAll.
 // First byte index is 0
 // TODO Benchmark ReadAll against specific byte by byte code
-func ReadCloserCompare(got io.ReadCloser, want string) error {
+// Options are the ones of FileCompare. The response is then read completely.
+func ReadCloserCompare(got io.ReadCloser, want string, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.compareOptions(); err != nil {
+		return err
+	}
+	useGolden(want)
 	wantf, err := os.Open(want)
 	if err != nil {
 		return err
@@ -231,7 +241,16 @@ func ReadCloserCompare(got io.ReadCloser, want string) error {
 
 	// Build got filename.
 	fileg := callerName("readclosercomparedefault")
-	gotf := fmt.Sprintf("got_%s", fileg)
+	caller := callerFunc(fileg)
+	if o.transforms() {
+		all, err := io.ReadAll(got)
+		if err != nil {
+			return fmt.Errorf("%s: %v", fileg, err)
+		}
+		return o.compareDump(all, want, caller)
+	}
+	read := new(bytes.Buffer) // Bytes of the response read by the comparison
+	gotr := io.TeeReader(got, read)
 
 	// Actual comparison
 	wantb, gotb := make([]byte, 1), make([]byte, 1)
@@ -243,7 +262,7 @@ func ReadCloserCompare(got io.ReadCloser, want string) error {
 				return err // file reading failed, the read error is returned
 			}
 
-			n, err = got.Read(gotb)
+			n, err = gotr.Read(gotb)
 			// Requires git 2.22.0 on Windows
 			if err == io.EOF { // If EOF produced, buffer is too short (and empty)
 				wantfInfo, _ := wantf.Stat()
@@ -254,14 +273,18 @@ func ReadCloserCompare(got io.ReadCloser, want string) error {
 						return nil
 					}
 				}
-				return fmt.Errorf("%s : got %v, want %q at %d. Response is missing %d",
+				err = fmt.Errorf("%s : got %v, want %q at %d. Response is missing %d",
 					fileg, err, wantb, index, wantfInfo.Size()-int64(index))
+				dumpGot(Artifact{Caller: caller, Want: want, Offset: int64(index), Error: err.Error()}, read)
+				return err
 			} else if err != nil && err != io.EOF {
 				return fmt.Errorf("%s: %v\n", fileg, err)
 			}
 			if !bytes.Equal(gotb, wantb) {
-				_ = ReadCloserToFile(gotf, got)
-				return fmt.Errorf("%s : got %q, want %q at %d", fileg, gotb, wantb, index)
+				err = fmt.Errorf("%s : got %q, want %q at %d", fileg, gotb, wantb, index)
+				dumpGot(Artifact{Caller: caller, Want: want, Offset: int64(index), Error: err.Error()},
+					io.MultiReader(read, got))
+				return err
 			}
 			index++
 		} else if err != nil && err != io.EOF {
@@ -269,29 +292,82 @@ func ReadCloserCompare(got io.ReadCloser, want string) error {
 		}
 	}
 	// EOF on reference file has been reached, check the got buffer
-	_, err = got.Read(gotb)
+	_, err = gotr.Read(gotb)
 	// If EOF is not produced, response is longer than file
 	if err != io.EOF {
-		// The read byte of the response is not written to file
-		err := ReadCloserToFile(gotf, got)
-		if err == nil {
-			gotInfo, _ := os.Stat(gotf)
-			return fmt.Errorf("%s : got response is too long by %d. Last read byte %q", fileg, gotInfo.Size(), gotb)
+		if _, err = io.ReadAll(gotr); err != nil {
+			return fmt.Errorf("%s : got response is too long. Reading failed with %v", fileg, err)
 		}
-		return fmt.Errorf("%s : got response is too long. Writing file failed with %v", fileg, err)
+		err = fmt.Errorf("%s : got response is too long by %d. Last read byte %q", fileg, read.Len()-index, gotb)
+		dumpGot(Artifact{Caller: caller, Want: want, Offset: int64(index), Error: err.Error()}, read)
+		return err
 	}
 	return nil
 }
 
+// compareDecoded compares the transformed contents of got and of the file want.
+func (o *options) compareDecoded(got []byte, want string) error {
+	wantb, err := os.ReadFile(want)
+	if err != nil {
+		return err
+	}
+	if o.unordered {
+		return o.compareUnordered(o.prepare(got), o.prepare(wantb))
+	}
+	return compareTexts(o.prepare(got), o.prepare(wantb))
+}
+
+// compareDump compares like compareDecoded and writes the got file of caller when contents differ.
+func (o *options) compareDump(got []byte, want, caller string) error {
+	err := o.compareDecoded(got, want)
+	var m *MismatchError
+	var u *UnorderedError
+	switch {
+	case errors.As(err, &m):
+		dumpGot(Artifact{Caller: caller, Want: want, Offset: m.Got.Offset, Error: err.Error()}, bytes.NewReader(got))
+	case errors.As(err, &u):
+		dumpGot(Artifact{Caller: caller, Want: want, Error: err.Error()}, bytes.NewReader(got))
+	}
+	return err
+}
+
+// callerFunc returns the package qualified name of the function that called the testingfiles func.
+// It returns the default if none is found.
+func callerFunc(d string) string {
+	i, _, _, ok := runtime.Caller(2) // Skipping test and testingfile func
+	if !ok {
+		return d
+	}
+	return runtime.FuncForPC(i).Name()
+}
+
 // callerName returns the name of the function that called the testingfiles func.
 // It returns the default if none is found.
+// Closures are named after their enclosing func. Use GoldenName to name files after a test.
 func callerName(d string) (f string) {
 	i, _, _, _ := runtime.Caller(2) // Skipping test and testingfile func
-	// TODO Migrate to Caller name
-	funcname := strings.SplitAfter(filepath.Base(runtime.FuncForPC(i).Name()), ".")
-	if len(funcname) == 1 {
+	_, funcname, found := strings.Cut(filepath.Base(runtime.FuncForPC(i).Name()), ".")
+	if !found || funcname == "" {
 		log.Printf("callerName: func name not found")
 		return d // name is empty
 	}
-	return funcname[1]
+	return trimClosure(funcname)
+}
+
+// trimClosure removes the suffixes like .func1 or .func1.2 which the compiler adds to closures.
+func trimClosure(funcname string) string {
+	for {
+		i := strings.LastIndexByte(funcname, '.')
+		if i == -1 {
+			return funcname
+		}
+		s := funcname[i+1:]
+		for _, prefix := range []string{"func", "deferwrap", "gowrap"} {
+			s = strings.TrimPrefix(s, prefix)
+		}
+		if s == "" || strings.Trim(s, "0123456789") != "" {
+			return funcname
+		}
+		funcname = funcname[:i]
+	}
 }
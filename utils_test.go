@@ -5,6 +5,7 @@ package testingfiles
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -44,9 +45,11 @@ func TestMain(m *testing.M) {
 			if err != nil {
 				log.Fatalf("create want file failed with %v", err)
 			}
+			wantb, err = os.ReadFile(wantf)
+		} else {
+			// File updates will occur in the tests
+			wantb, err = io.ReadAll(resp.Body)
 		}
-		// File updates will occur in the tests
-		wantb, err = io.ReadAll(resp.Body)
 		if err != nil {
 			log.Fatalf("%v\n", err)
 		}
@@ -76,6 +79,20 @@ func TestMain(m *testing.M) {
 	os.Exit(e)
 }
 
+// writeFiles writes the files of dir with their content. Missing parent directories are created.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for f, content := range files {
+		name := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
 // Buffer is used as a string and produces a file
 // The check is using FileCompare to detect an error
 // The error is used for the test and this method by the Benchmark
@@ -302,10 +319,10 @@ func TestBufferToFilePanicFilename(t *testing.T) {
 	t.Errorf("BufferToFile did not panic")
 }
 
-func TestReadCloserToFilePanicFilename(t *testing.T) {
-	defer recoverFileSystem(t)
-	_ = ReadCloserToFile("", nil)
-	t.Errorf("ReadCloserToFile did not panic")
+func TestReadCloserToFileFilename(t *testing.T) {
+	if err := ReadCloserToFile("", io.NopCloser(strings.NewReader(""))); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
 }
 
 func TestBufferCompareFileFail(t *testing.T) {
@@ -342,10 +359,10 @@ func TestBufferToFilePanicContent(t *testing.T) {
 	t.Fatalf("nil content did not panic")
 }
 
-func TestReadCloserToFilePanicContent(t *testing.T) {
-	defer recoverNilContent(t)
-	_ = ReadCloserToFile("nilcontent", nil)
-	t.Fatalf("nil content did not panic")
+func TestReadCloserToFileContent(t *testing.T) {
+	if err := ReadCloserToFile("nilcontent", nil); err == nil {
+		t.Error("nil content is written")
+	}
 }
 
 func TestFileCompareDoesNotExist(t *testing.T) {
@@ -442,14 +459,9 @@ func TestReadCloserCompareDifference(t *testing.T) {
 	}
 	b.Reset()
 	b.WriteString("ab")
-	// Length should be 1 but the last byte read from the response is not written to file
-	// but can be recovered in the error message
+	// The got file holds the complete response
 	if err := ReadCloserCompare(io.NopCloser(b), "afile"); !strings.Contains(fmt.Sprint(err),
-		`got response is too long by 0. Last read byte`) {
-		fn := "TestReadCloserCompareDifference"
-		if fstat, errf := os.Stat(fn); errf == nil && fstat.Size() == 0 {
-			_ = os.Remove(fn)
-		}
+		`got response is too long by 1. Last read byte "b"`) {
 		t.Errorf("%v", err)
 	}
 	b.Reset()
@@ -485,3 +497,32 @@ func TestStringToFilePanicContent(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestTrimClosure(t *testing.T) {
+	for s, want := range map[string]string{
+		"TestA":                "TestA",
+		"TestA.func1":          "TestA",
+		"TestA.func1.2":        "TestA",
+		"TestA.deferwrap1":     "TestA",
+		"(*T).m":               "(*T).m",
+		"(*T).m1":              "(*T).m1",
+		"TestA.TestB.func3.10": "TestA.TestB",
+	} {
+		if got := trimClosure(s); got != want {
+			t.Errorf("%s: got %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestCallerName_closure(t *testing.T) {
+	func() {
+		if got := callerNameOf(); got != "TestCallerName_closure" {
+			t.Errorf("got %s, want %s", got, t.Name())
+		}
+	}()
+}
+
+// callerNameOf stands for a testingfiles func.
+func callerNameOf() string {
+	return callerName("default")
+}
@@ -0,0 +1,48 @@
+package testingfiles
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MissingLinesError reports the lines of a baseline which are missing from a file.
+type MissingLinesError struct {
+	File  string
+	Lines []string
+}
+
+func (e *MissingLinesError) Error() string {
+	if len(e.Lines) == 1 {
+		return fmt.Sprintf("%s: common feature is missing: %s", e.File, e.Lines[0])
+	}
+	return fmt.Sprintf("%s: %d common features are missing:\n\t%s", e.File, len(e.Lines),
+		strings.Join(e.Lines, "\n\t"))
+}
+
+// ValidateBaseline checks that the lines of baselinef are in every file selected by globf, as
+// CreateSupplements requires. Lines are compared like CreateSupplements does with the same options.
+// Every file missing lines is reported by a *MissingLinesError and all are joined in the returned error.
+// Other errors like an unreadable file stop the validation.
+func ValidateBaseline(contextFilesPath, globf, baselinef string, opts ...Option) error {
+	_, _, err := newOptions(opts).supplements(contextFilesPath, globf, baselinef)
+	return err
+}
+
+// MissingLines returns the missing lines by file of the errors of ValidateBaseline or CreateSupplements.
+func MissingLines(err error) map[string][]string {
+	m := make(map[string][]string)
+	var walk func(err error)
+	walk = func(err error) {
+		var missing *MissingLinesError
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range joined.Unwrap() {
+				walk(e)
+			}
+		} else if errors.As(err, &missing) {
+			m[missing.File] = append(m[missing.File], missing.Lines...)
+		}
+	}
+	walk(err)
+	return m
+}
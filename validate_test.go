@@ -0,0 +1,52 @@
+package testingfiles
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateBaseline(t *testing.T) {
+	w := t.TempDir()
+	writeFiles(t, w, map[string]string{
+		commonf:      "a\nb\nc\n",
+		"case_1.txt": "a\nb\nc\nd\n",
+		"case_2.txt": "b\nd\n",
+		"case_3.txt": "a\nb\n",
+	})
+	want := map[string][]string{
+		filepath.Join(w, "case_2.txt"): {"a", "c"},
+		filepath.Join(w, "case_3.txt"): {"c"},
+	}
+	for _, opts := range [][]Option{nil, {KeepOrder()}, {KeepOrder(), Multiset()}, {FoldCase()}} {
+		err := ValidateBaseline(w, globf, commonf, opts...)
+		var missing *MissingLinesError
+		if !errors.As(err, &missing) {
+			t.Fatalf("got %v, want a *MissingLinesError", err)
+		}
+		got := MissingLines(err)
+		if len(got) != len(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		for f, lines := range want {
+			if !slices.Equal(got[f], lines) {
+				t.Errorf("%s: got %q, want %q", f, got[f], lines)
+			}
+		}
+	}
+	err := CreateSupplements(w, globf, commonf)
+	for _, s := range []string{"case_2.txt: 2 common features are missing:\n\ta\n\tc", "case_3.txt: common feature is missing: c"} {
+		if err == nil || !strings.Contains(err.Error(), s) {
+			t.Errorf("got %v, want %q", err, s)
+		}
+	}
+	err = CreateSupplementsStream(w, globf, commonf)
+	if got := MissingLines(err); len(got) != len(want) {
+		t.Errorf("stream: got %v, want %v", got, want)
+	}
+	if err = ValidateBaseline(w, "case_1.txt", commonf); err != nil {
+		t.Error(err)
+	}
+}
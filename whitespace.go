@@ -0,0 +1,94 @@
+package testingfiles
+
+// IgnoreTrailingSpace ignores white space at the end of lines when contents are compared, like diff -Z.
+// A carriage return before a new line is white space.
+func IgnoreTrailingSpace() Option {
+	return func(o *options) {
+		o.trailingSpace = true
+	}
+}
+
+// CollapseSpace ignores changes in the amount of white space when contents are compared, like diff -b.
+// Every run of white space compares like a single space and white space at the end of lines is ignored.
+func CollapseSpace() Option {
+	return func(o *options) {
+		o.collapseSpace = true
+	}
+}
+
+// IgnoreAllSpace ignores all white space within lines when contents are compared, like diff -w.
+func IgnoreAllSpace() Option {
+	return func(o *options) {
+		o.allSpace = true
+	}
+}
+
+// IgnoreBlankLines ignores lines holding only white space when contents are compared, like diff -B.
+func IgnoreBlankLines() Option {
+	return func(o *options) {
+		o.blankLines = true
+	}
+}
+
+// spaces reports whether white space is ignored by comparisons.
+func (o *options) spaces() bool {
+	return o.trailingSpace || o.collapseSpace || o.allSpace || o.blankLines
+}
+
+// isSpace reports whether b is white space within a line.
+func isSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+// mapSpace removes or collapses the white space of t as set by the options.
+// A collapsed run of white space is located at its first byte.
+func (t text) mapSpace(o *options) text {
+	m := text{raw: t.raw, order: t.order}
+	add := func(b byte, i int) {
+		m.data = append(m.data, b)
+		m.off = append(m.off, t.off[i])
+	}
+	for start := 0; start < len(t.data); {
+		end := start
+		blank := true
+		for end < len(t.data) && t.data[end] != '\n' {
+			blank = blank && isSpace(t.data[end])
+			end++
+		}
+		if blank && o.blankLines && (start != end || end < len(t.data)) {
+			start = end + 1
+			continue
+		}
+		for i := start; i < end; {
+			if !isSpace(t.data[i]) {
+				add(t.data[i], i)
+				i++
+				continue
+			}
+			j := i
+			for j < end && isSpace(t.data[j]) {
+				j++
+			}
+			switch {
+			case o.allSpace || j == end && (o.trailingSpace || o.collapseSpace):
+			case o.collapseSpace:
+				add(' ', i)
+			default:
+				for k := i; k < j; k++ {
+					add(t.data[k], k)
+				}
+			}
+			i = j
+		}
+		if end < len(t.data) {
+			add('\n', end)
+		}
+		start = end + 1
+	}
+	m.off = append(m.off, t.off[len(t.data)])
+	return m
+}
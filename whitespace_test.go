@@ -0,0 +1,75 @@
+package testingfiles
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileCompare_space(t *testing.T) {
+	w := t.TempDir()
+	want := filepath.Join(w, "want")
+	if err := os.WriteFile(want, []byte("func f() {\n\treturn a + b\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		got  string
+		opts []Option
+	}{
+		{"trailing", "func f() { \r\n\treturn a + b\t\n}\n", []Option{IgnoreTrailingSpace()}},
+		{"collapse", "func  f()\t{ \n  return a  +  b\n}\n", []Option{CollapseSpace()}},
+		{"all", "func f(){\nreturn a+b \n}\n", []Option{IgnoreAllSpace()}},
+		{"blank", "\nfunc f() {\n \n\treturn a + b\n\n}\n\n", []Option{IgnoreBlankLines()}},
+		{"combined", "func f() {\r\n\r\n\treturn a + b\r\n}\r\n", []Option{IgnoreTrailingSpace(), IgnoreBlankLines()}},
+	} {
+		got := filepath.Join(w, "got")
+		if err := os.WriteFile(got, []byte(tc.got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := FileCompare(got, want); err == nil {
+			t.Errorf("%s: unexpected success without option", tc.name)
+		}
+		if err := FileCompare(got, want, tc.opts...); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if err := BufferCompare(bytes.NewBufferString(tc.got), want, tc.opts...); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+}
+
+func TestFileCompare_spaceMismatch(t *testing.T) {
+	w := t.TempDir()
+	want := filepath.Join(w, "want")
+	if err := os.WriteFile(want, []byte("a b\nc d\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		got  string
+		opts []Option
+		pos  Position
+		text string
+	}{
+		{"trailing", "a b  \nc  d\n", []Option{IgnoreTrailingSpace()},
+			Position{Offset: 8, Line: 2, Column: 3}, " "},
+		{"collapse", "a   b\nc\td\ne\n", []Option{CollapseSpace()},
+			Position{Offset: 10, Line: 3, Column: 1}, "e"},
+		{"all", "a b\n c x\n", []Option{IgnoreAllSpace()},
+			Position{Offset: 7, Line: 2, Column: 4}, "x"},
+		{"blank", "\n\na b\nc e\n", []Option{IgnoreBlankLines()},
+			Position{Offset: 8, Line: 4, Column: 3}, "e"},
+	} {
+		err := BufferCompare(bytes.NewBufferString(tc.got), want, tc.opts...)
+		var m *MismatchError
+		if !errors.As(err, &m) {
+			t.Fatalf("%s: got %v, want a *MismatchError", tc.name, err)
+		}
+		if m.Got != tc.pos || m.GotText != tc.text {
+			t.Errorf("%s: got %v %q, want %v %q", tc.name, m.Got, m.GotText, tc.pos, tc.text)
+		}
+	}
+}
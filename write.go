@@ -0,0 +1,226 @@
+package testingfiles
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// ErrConflict is returned when a test writes, using StringToFile or BufferToFile, a different content
+// to a file which another test wrote and which is unchanged since.
+var ErrConflict = errors.New("conflicting content")
+
+// pathLocks holds a *sync.Mutex by absolute path to serialize writers of the same file.
+var pathLocks sync.Map
+
+// written records the digest of the last content written to a path and the test which wrote it.
+var written = struct {
+	sync.Mutex
+	m map[string]writeRecord
+}{m: make(map[string]writeRecord)}
+
+type writeRecord struct {
+	sum    [sha256.Size]byte
+	writer string
+}
+
+// WriteFile writes content to the file name. Missing parent directories are created.
+// The file is written atomically and concurrent writers are serialized.
+// An existing file keeps its permissions unless FileMode is used.
+func WriteFile(name string, content []byte, opts ...Option) error {
+	return writeFile(name, newOptions(opts), func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+}
+
+// WriteBuffer writes the unread content of the buffer to the file name without consuming it.
+// It behaves like WriteFile.
+func WriteBuffer(name string, content *bytes.Buffer, opts ...Option) error {
+	if content == nil {
+		return fmt.Errorf("%s: buffer is nil", name)
+	}
+	return WriteFile(name, content.Bytes(), opts...)
+}
+
+// WriteReadCloser writes the content read from r to the file name and closes r.
+// It behaves like WriteFile.
+func WriteReadCloser(name string, r io.ReadCloser, opts ...Option) error {
+	if r == nil {
+		return fmt.Errorf("%s: reader is nil", name)
+	}
+	err := writeFile(name, newOptions(opts), func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+	if errc := r.Close(); err == nil {
+		err = errc
+	}
+	return err
+}
+
+// writeFile creates the parent directories of name and writes it atomically using write.
+// Writers of the same path are serialized.
+func writeFile(name string, o *options, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	unlock, err := lockPath(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return writeAtomic(name, o.mode, write)
+}
+
+// writeGolden behaves like writeFile for the files written by tests, like golden files.
+// ErrConflict is returned, and name is unchanged, when another test wrote a different content to name
+// which is still on disk. The record of a file which was removed or replaced is dropped.
+func writeGolden(name string, o *options, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	p, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+	unlock, err := lockPath(p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	written.Lock()
+	prev, ok := written.m[p]
+	written.Unlock()
+	if ok && !sameSum(p, prev.sum) {
+		// The earlier content was removed or replaced
+		written.Lock()
+		delete(written.m, p)
+		written.Unlock()
+		ok = false
+	}
+	r := writeRecord{writer: writerName()}
+	err = writeAtomic(name, o.mode, func(w io.Writer) error {
+		h := sha256.New()
+		if err := write(io.MultiWriter(w, h)); err != nil {
+			return err
+		}
+		h.Sum(r.sum[:0])
+		if ok && prev.writer != r.writer && prev.sum != r.sum {
+			return fmt.Errorf("%w: %s written by %s differs from %s", ErrConflict, name, r.writer, prev.writer)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	written.Lock()
+	written.m[p] = r
+	written.Unlock()
+	return nil
+}
+
+// sameSum reports whether the file p exists and its content has the digest sum.
+func sameSum(p string, sum [sha256.Size]byte) bool {
+	f, err := os.Open(p)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false
+	}
+	var got [sha256.Size]byte
+	h.Sum(got[:0])
+	return got == sum
+}
+
+// lockPath serializes the writers of name in the process and, using an advisory lock on its directory,
+// across processes where available. The returned func releases the locks.
+func lockPath(name string) (unlock func(), err error) {
+	p, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	v, _ := pathLocks.LoadOrStore(p, new(sync.Mutex))
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	unlockDir, err := lockDir(filepath.Dir(p))
+	if err != nil {
+		mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlockDir()
+		mu.Unlock()
+	}, nil
+}
+
+// writeAtomic writes name using write. Content goes to a temporary file of the same directory which is synced
+// and renamed when complete. Readers never see a partial file.
+// An existing file keeps its permissions unless perm is set. A new file uses perm or 0644.
+func writeAtomic(name string, perm fs.FileMode, write func(w io.Writer) error) (err error) {
+	if name == "" {
+		return &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	fi, err := os.Stat(name)
+	switch {
+	case err == nil && fi.IsDir():
+		// A directory is not replaced
+		return &fs.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	case err == nil && perm == 0:
+		perm = fi.Mode().Perm()
+	case perm == 0:
+		perm = 0644
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// writerName returns the name of the first func outside the package which is usually the test.
+// Tests of the package are considered outside. Closures like subtests are distinct writers.
+func writerName() string {
+	pc := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(2, pc)])
+	pkg := "github.com/iwdgo/testingfiles."
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkg) || strings.HasSuffix(f.File, "_test.go") {
+			return f.Function
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package testingfiles
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBufferToFile_parallel(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "parallel")
+	content := strings.Repeat("parallel writers\n", 1000)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			BufferToFile(name, bytes.NewBufferString(content))
+		}()
+	}
+	wg.Wait()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != content {
+		t.Errorf("got %d bytes, want %d", len(b), len(content))
+	}
+	fl, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fl) != 1 {
+		t.Errorf("temporary files are left: %v", fl)
+	}
+}
+
+func TestWriteAtomic_conflict(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "conflict")
+	t.Run("first", func(t *testing.T) {
+		BufferToFile(name, bytes.NewBufferString("first"))
+		// Same writer can update its file
+		BufferToFile(name, bytes.NewBufferString("updated"))
+	})
+	t.Run("same", func(t *testing.T) {
+		if err := ReadCloserToFile(name, io.NopCloser(strings.NewReader("updated"))); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("second", func(t *testing.T) {
+		defer func() {
+			err, _ := recover().(error)
+			if !errors.Is(err, ErrConflict) {
+				t.Errorf("got %v, want %v", err, ErrConflict)
+			}
+		}()
+		BufferToFile(name, bytes.NewBufferString("second"))
+	})
+	if b, err := os.ReadFile(name); err != nil || string(b) != "updated" {
+		t.Errorf("got %q, %v, want %q", b, err, "updated")
+	}
+}
+
+// Files removed or replaced since the write of another test are written again without conflict.
+func TestWriteAtomic_sequential(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "tmp.txt")
+	t.Run("removed", func(t *testing.T) {
+		BufferToFile(name, bytes.NewBufferString("one"))
+		if err := os.Remove(name); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("rewritten", func(t *testing.T) {
+		BufferToFile(name, bytes.NewBufferString("two"))
+		if err := os.WriteFile(name, []byte("three"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("replaced", func(t *testing.T) {
+		BufferToFile(name, bytes.NewBufferString("four"))
+		if err := WriteFile(name, []byte("replaced")); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("last", func(t *testing.T) {
+		BufferToFile(name, bytes.NewBufferString("five"))
+	})
+	if b, err := os.ReadFile(name); err != nil || string(b) != "five" {
+		t.Errorf("got %q, %v, want %q", b, err, "five")
+	}
+}
+
+// Writers other than test helpers replace files whatever wrote them before.
+func TestWriteFile_noconflict(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "noconflict")
+	for _, content := range []string{"first", "second"} {
+		t.Run(content, func(t *testing.T) {
+			if err := WriteFile(name, []byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	if b, err := os.ReadFile(name); err != nil || string(b) != "second" {
+		t.Errorf("got %q, %v, want %q", b, err, "second")
+	}
+}
+
+func TestWriteFile_keepmode(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "keepmode")
+	if err := os.WriteFile(name, []byte("a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(name, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(name, []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fi.Mode().Perm(), fs.FileMode(0600); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWriteAtomic_emptyname(t *testing.T) {
+	err := writeAtomic("", 0644, nil)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+// closeRecorder records whether Close was called.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "sub", "dir", "file")
+	if err := WriteFile(name, []byte("a"), FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fi.Mode().Perm(), fs.FileMode(0600); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Parent is a file
+	if err = WriteFile(filepath.Join(name, "file"), nil); err == nil {
+		t.Error("writing under a file succeeded")
+	}
+	if err = WriteFile("", nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestWriteBuffer(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "buffer")
+	b := bytes.NewBufferString("buffer")
+	if err := WriteBuffer(name, b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != len("buffer") {
+		t.Errorf("buffer is consumed")
+	}
+	if got, err := os.ReadFile(name); err != nil || string(got) != "buffer" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if err := WriteBuffer(name, nil); err == nil {
+		t.Error("nil buffer is written")
+	}
+}
+
+func TestWriteReadCloser(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "readcloser")
+	r := &closeRecorder{Reader: strings.NewReader("readcloser")}
+	if err := WriteReadCloser(name, r); err != nil {
+		t.Fatal(err)
+	}
+	if !r.closed {
+		t.Error("reader is not closed")
+	}
+	if got, err := os.ReadFile(name); err != nil || string(got) != "readcloser" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	r = &closeRecorder{Reader: strings.NewReader("")}
+	if err := WriteReadCloser("", r); err == nil || !r.closed {
+		t.Errorf("got %v, closed %v", err, r.closed)
+	}
+	if err := WriteReadCloser(name, nil); err == nil {
+		t.Error("nil reader is written")
+	}
+}